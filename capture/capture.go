@@ -0,0 +1,132 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Magic starts every capture file. It is followed by chunks, each an int64
+// nanosecond offset from the start of the capture, a uint32 length and the
+// bytes exactly as they were read from the port, all big endian.
+const Magic = "serial-plotter capture v1\n"
+
+const maxChunkLength = 1 << 20
+
+type Chunk struct {
+	Offset time.Duration
+	Data   []byte
+}
+
+// Writer tees raw reads to a capture file. It is safe to write from the
+// read loop while another goroutine closes it.
+type Writer struct {
+	mutex  sync.Mutex
+	file   io.WriteCloser
+	writer *bufio.Writer
+	start  time.Time
+}
+
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture %v", err)
+	}
+	return NewWriter(file)
+}
+
+// NewWriter starts a capture on file, which the writer closes.
+func NewWriter(file io.WriteCloser) (*Writer, error) {
+	w := &Writer{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		start:  time.Now(),
+	}
+	_, err := w.writer.WriteString(Magic)
+	if err == nil {
+		err = w.writer.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write records a chunk read at the given time.
+func (w *Writer) Write(received time.Time, data []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return fmt.Errorf("capture is closed")
+	}
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint64(header, uint64(received.Sub(w.start)))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(data)))
+	_, err := w.writer.Write(header)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(data)
+	if err != nil {
+		return err
+	}
+	// Flush every chunk so a crash loses nothing that was read
+	return w.writer.Flush()
+}
+
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.writer.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	return err
+}
+
+// IsCapture reports whether the reader starts with the capture magic. The
+// reader is left unread.
+func IsCapture(reader *bufio.Reader) bool {
+	header, err := reader.Peek(len(Magic))
+	return err == nil && string(header) == Magic
+}
+
+// ReadAll reads every chunk of a capture.
+func ReadAll(reader io.Reader) ([]Chunk, error) {
+	magic := make([]byte, len(Magic))
+	_, err := io.ReadFull(reader, magic)
+	if err != nil || string(magic) != Magic {
+		return nil, fmt.Errorf("not a serial plotter capture")
+	}
+	chunks := []Chunk{}
+	header := make([]byte, 12)
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err != nil {
+			// The end of the file, or a partial chunk left by a crash
+			return chunks, nil
+		}
+		length := binary.BigEndian.Uint32(header[8:])
+		if length > maxChunkLength {
+			return chunks, fmt.Errorf("capture chunk length %d is too large", length)
+		}
+		data := make([]byte, length)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return chunks, nil
+		}
+		chunks = append(chunks, Chunk{
+			Offset: time.Duration(binary.BigEndian.Uint64(header)),
+			Data:   data,
+		})
+	}
+}
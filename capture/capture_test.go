@@ -0,0 +1,111 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.bin")
+	writer, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := [][]byte{[]byte("a:1\n"), {}, {0x00, 0xff, '\n'}}
+	for i, chunk := range chunks {
+		err := writer.Write(writer.start.Add(time.Duration(i)*time.Second), chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Write(time.Now(), []byte("late"))
+	if err == nil {
+		t.Error("Write after Close succeeded")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	if !IsCapture(reader) {
+		t.Fatal("IsCapture = false, want true")
+	}
+	got, err := ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(chunks) {
+		t.Fatalf("read %d chunks, want %d", len(got), len(chunks))
+	}
+	for i, chunk := range got {
+		if chunk.Offset != time.Duration(i)*time.Second {
+			t.Errorf("chunk %d offset = %v, want %ds", i, chunk.Offset, i)
+		}
+		if !bytes.Equal(chunk.Data, chunks[i]) {
+			t.Errorf("chunk %d = %q, want %q", i, chunk.Data, chunks[i])
+		}
+	}
+}
+
+// capture builds a capture of the chunks.
+func capture(chunks ...string) []byte {
+	data := []byte(Magic)
+	for i, chunk := range chunks {
+		header := make([]byte, 12)
+		header[4] = byte(i)
+		header[11] = byte(len(chunk))
+		data = append(data, header...)
+		data = append(data, chunk...)
+	}
+	return data
+}
+
+func TestReadAllTruncated(t *testing.T) {
+	data := capture("a:1\n", "a:2\n")
+	tests := []struct {
+		name   string
+		data   []byte
+		chunks int
+	}{
+		{name: "complete", data: data, chunks: 2},
+		{name: "partial data", data: data[:len(data)-2], chunks: 1},
+		{name: "partial header", data: data[:len(data)-4-6], chunks: 1},
+		{name: "magic only", data: []byte(Magic), chunks: 0},
+	}
+	for _, test := range tests {
+		got, err := ReadAll(bytes.NewReader(test.data))
+		if err != nil {
+			t.Errorf("%s: ReadAll error = %v", test.name, err)
+			continue
+		}
+		if len(got) != test.chunks {
+			t.Errorf("%s: read %d chunks, want %d", test.name, len(got), test.chunks)
+		}
+	}
+}
+
+func TestReadAllInvalid(t *testing.T) {
+	if IsCapture(bufio.NewReader(bytes.NewReader([]byte("a:1\n")))) {
+		t.Error("IsCapture of plain text = true, want false")
+	}
+	_, err := ReadAll(bytes.NewReader([]byte("a:1\n")))
+	if err == nil {
+		t.Error("ReadAll of plain text succeeded")
+	}
+	data := []byte(Magic)
+	data = append(data, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff)
+	_, err = ReadAll(bytes.NewReader(data))
+	if err == nil {
+		t.Error("ReadAll of an oversized chunk succeeded")
+	}
+}
@@ -0,0 +1,131 @@
+// Command reparse runs a raw serial capture through the serial decoder and
+// prints the parsed samples as CSV, so a parse problem seen in the field can
+// be reproduced exactly and tried against other parser settings.
+//
+//	go run ./cmd/reparse -terminator '\r\n' -non-finite Gap capture.bin
+//	go run ./cmd/reparse -framing COBS -layout 'u32 t; f32 temp' capture.bin
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/taylorcoons/serial-plotter/capture"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+func main() {
+	terminatorOption := flag.String("terminator", `\n`, "line terminator, \\n, \\r\\n, \\r, a single character or a hex byte such as 0x03")
+	maxLineLength := flag.Int("max-line-length", serial.DefaultMaxLineLength, "longest line accepted before it is counted as a framing error")
+	nonFiniteOption := flag.String("non-finite", serial.DropNonFinite.String(), "handling of NaN and infinite values, Drop, Gap or Clamp")
+	timestampField := flag.String("timestamp-field", "", "column carrying the device timestamp")
+	timestampUnitOption := flag.String("timestamp-unit", "ms", "unit of the device timestamp, s, ms or us")
+	grammarOption := flag.String("grammar", serial.ArduinoGrammar.String(), "grammar of lines, Arduino or 'NMEA 0183'")
+	framingOption := flag.String("framing", serial.LineFraming.String(), "framing of the capture, Lines, COBS, SLIP or Sync+Length+CRC16")
+	layoutOption := flag.String("layout", "", "record layout of binary frames, such as 'u32 t; i16 ax, ay, az; f32 temp'")
+	byteOrderOption := flag.String("byte-order", "little", "byte order of binary records, little or big")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] capture\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	terminator, err := serial.ParseTerminator(*terminatorOption)
+	if err != nil {
+		fatal(err)
+	}
+	nonFinite, err := serial.ParseNonFinitePolicy(*nonFiniteOption)
+	if err != nil {
+		fatal(err)
+	}
+	timestampUnit, err := serial.ParseTimestampUnit(*timestampUnitOption)
+	if err != nil {
+		fatal(err)
+	}
+	grammar, err := serial.ParseGrammar(*grammarOption)
+	if err != nil {
+		fatal(err)
+	}
+	framing, err := serial.ParseFraming(*framingOption)
+	if err != nil {
+		fatal(err)
+	}
+	format := serial.Format{
+		Framing:        framing,
+		Terminator:     terminator,
+		Grammar:        grammar,
+		MaxLineLength:  *maxLineLength,
+		NonFinite:      nonFinite,
+		TimestampField: *timestampField,
+		TimestampUnit:  timestampUnit,
+	}
+	if framing != serial.LineFraming {
+		byteOrder, err := serial.ParseByteOrder(*byteOrderOption)
+		if err != nil {
+			fatal(err)
+		}
+		format.Layout, err = serial.ParseLayout(*layoutOption, byteOrder)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	decoder := format.NewDecoder()
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	defer file.Close()
+	chunks, err := capture.ReadAll(bufio.NewReader(file))
+	if err != nil {
+		fatal(err)
+	}
+
+	out := csv.NewWriter(os.Stdout)
+	out.Write([]string{"elapsed_s", "device_time_s", "channel", "value"})
+	parseErrors := 0
+	for _, chunk := range chunks {
+		decoder.Write(chunk.Data)
+		for {
+			samples, ok, err := decoder.Next()
+			if !ok {
+				break
+			}
+			if err != nil {
+				parseErrors++
+				fmt.Fprintf(os.Stderr, "%s: %v\n", chunk.Offset, err)
+				continue
+			}
+			for _, sample := range samples {
+				deviceTime := ""
+				if sample.HasDeviceTime {
+					deviceTime = strconv.FormatFloat(sample.DeviceTime.Seconds(), 'f', -1, 64)
+				}
+				out.Write([]string{
+					strconv.FormatFloat(chunk.Offset.Seconds(), 'f', -1, 64),
+					deviceTime,
+					sample.Name,
+					strconv.FormatFloat(float64(sample.Value), 'g', -1, 32),
+				})
+			}
+		}
+	}
+	out.Flush()
+	fmt.Fprintf(os.Stderr, "%d chunks, %d parse errors, %d framing errors\n", len(chunks), parseErrors, decoder.FramingErrors())
+	if err := out.Error(); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
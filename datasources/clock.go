@@ -0,0 +1,38 @@
+package datasources
+
+import "time"
+
+// DeviceClock keeps the device time of each channel moving forward when a
+// device's clock restarts, such as a board resetting on reconnect, so points
+// stay in time order on the plot.
+type DeviceClock struct {
+	last   map[string]Sample
+	offset map[string]time.Duration
+}
+
+func NewDeviceClock() *DeviceClock {
+	return &DeviceClock{
+		last:   map[string]Sample{},
+		offset: map[string]time.Duration{},
+	}
+}
+
+// Monotonic returns the sample with its device time moved on past any
+// restarts of the device clock. It reports whether the clock went back at
+// this sample, in which case the sample is placed after the previous one by
+// the time between receiving them and a gap belongs before it.
+func (c *DeviceClock) Monotonic(sample Sample) (Sample, bool) {
+	if !sample.HasDeviceTime {
+		return sample, false
+	}
+	restarted := false
+	last, ok := c.last[sample.Name]
+	if ok && sample.DeviceTime+c.offset[sample.Name] < last.DeviceTime {
+		elapsed := max(0, sample.Received.Sub(last.Received))
+		c.offset[sample.Name] = last.DeviceTime + elapsed - sample.DeviceTime
+		restarted = true
+	}
+	sample.DeviceTime += c.offset[sample.Name]
+	c.last[sample.Name] = sample
+	return sample, restarted
+}
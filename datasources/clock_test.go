@@ -0,0 +1,76 @@
+package datasources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviceClockMonotonic(t *testing.T) {
+	start := time.Now()
+	sample := func(name string, received, device time.Duration) Sample {
+		return Sample{Name: name, Received: start.Add(received), DeviceTime: device, HasDeviceTime: true}
+	}
+	tests := []struct {
+		name      string
+		samples   []Sample
+		want      []time.Duration
+		restarted []bool
+	}{
+		{
+			name:      "forward",
+			samples:   []Sample{sample("a", 0, 5*time.Second), sample("a", time.Second, 6*time.Second)},
+			want:      []time.Duration{5 * time.Second, 6 * time.Second},
+			restarted: []bool{false, false},
+		},
+		{
+			name: "restart",
+			samples: []Sample{
+				sample("a", 0, 5*time.Second),
+				sample("a", 2*time.Second, 0),
+				sample("a", 3*time.Second, time.Second),
+			},
+			want:      []time.Duration{5 * time.Second, 7 * time.Second, 8 * time.Second},
+			restarted: []bool{false, true, false},
+		},
+		{
+			name: "restart twice",
+			samples: []Sample{
+				sample("a", 0, 5*time.Second),
+				sample("a", time.Second, 3*time.Second),
+				sample("a", 2*time.Second, 0),
+			},
+			want:      []time.Duration{5 * time.Second, 6 * time.Second, 7 * time.Second},
+			restarted: []bool{false, true, true},
+		},
+		{
+			name:      "channels kept apart",
+			samples:   []Sample{sample("a", 0, 5*time.Second), sample("b", 0, time.Second)},
+			want:      []time.Duration{5 * time.Second, time.Second},
+			restarted: []bool{false, false},
+		},
+		{
+			name:      "equal times",
+			samples:   []Sample{sample("a", 0, time.Second), sample("a", 0, time.Second)},
+			want:      []time.Duration{time.Second, time.Second},
+			restarted: []bool{false, false},
+		},
+	}
+	for _, test := range tests {
+		clock := NewDeviceClock()
+		for i, input := range test.samples {
+			got, restarted := clock.Monotonic(input)
+			if got.DeviceTime != test.want[i] || restarted != test.restarted[i] {
+				t.Errorf("%s: sample %d = %v, %t, want %v, %t", test.name, i, got.DeviceTime, restarted, test.want[i], test.restarted[i])
+			}
+		}
+	}
+}
+
+func TestDeviceClockReceivedOnly(t *testing.T) {
+	clock := NewDeviceClock()
+	input := Sample{Name: "a", Value: 1, Received: time.Now()}
+	got, restarted := clock.Monotonic(input)
+	if got != input || restarted {
+		t.Errorf("Monotonic = %v, %t, want the sample unchanged", got, restarted)
+	}
+}
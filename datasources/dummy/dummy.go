@@ -1,10 +1,15 @@
 package dummy
 
 import (
+	"context"
 	"math"
 	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
 )
 
+const ChannelName = "Dummy"
+
 type Function func(index int) float32
 
 type Dummy struct {
@@ -54,18 +59,33 @@ func New(delay time.Duration, function Function) *Dummy {
 	}
 }
 
-func (p *Dummy) ResetIndex() {
+func (p *Dummy) Open(ctx context.Context) error {
 	p.index = 0
+	return nil
 }
 
 func (p *Dummy) SetFunction(function Function) {
 	p.function = function
 }
 
-func (p *Dummy) ReadSource() (float32, error) {
-	time.Sleep(p.delay)
+func (p *Dummy) Read(ctx context.Context) ([]datasources.Sample, error) {
+	timer := time.NewTimer(p.delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
 	defer func() {
 		p.index++
 	}()
-	return p.function(p.index), nil
+	return []datasources.Sample{{
+		Name:     ChannelName,
+		Value:    p.function(p.index),
+		Received: time.Now(),
+	}}, nil
+}
+
+func (p *Dummy) Close() error {
+	return nil
 }
@@ -0,0 +1,55 @@
+package dummy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		function Function
+		want     []float32
+	}{
+		{"constant", ConstantFunction, []float32{8, 8, 8}},
+		{"negative ramp", Neg100X, []float32{0, -100, -200}},
+		{"sawtooth", SawtoothFunction, []float32{0, 2, 4}},
+	}
+	for _, test := range tests {
+		source := New(time.Millisecond, test.function)
+		if err := source.Open(context.Background()); err != nil {
+			t.Fatalf("%s: Open returned error %v", test.name, err)
+		}
+		for i, want := range test.want {
+			data, err := source.Read(context.Background())
+			if err != nil || len(data) != 1 || data[0].Name != ChannelName || data[0].Value != want {
+				t.Errorf("%s: Read %d = %v, %v, want %v", test.name, i, data, err, want)
+			}
+		}
+		// Opening again starts the function over
+		source.Open(context.Background())
+		data, err := source.Read(context.Background())
+		if err != nil || len(data) != 1 || data[0].Value != test.want[0] {
+			t.Errorf("%s: Read after reopen = %v, %v, want %v", test.name, data, err, test.want[0])
+		}
+	}
+}
+
+func TestReadCancelled(t *testing.T) {
+	source := New(time.Hour, ConstantFunction)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	started := time.Now()
+	_, err := source.Read(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Read error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Read took %v to return after cancel", elapsed)
+	}
+}
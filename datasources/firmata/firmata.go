@@ -0,0 +1,306 @@
+package firmata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// DefaultSamplingInterval is StandardFirmata's own default.
+const DefaultSamplingInterval = 19 * time.Millisecond
+
+// handshakeTimeout is how long to wait for a board to describe its pins.
+// Opening the port resets most Arduinos, which then spend a couple of
+// seconds in the bootloader.
+const handshakeTimeout = 10 * time.Second
+
+// queryInterval is how often the capability queries are repeated during
+// the handshake, as queries sent while the board boots are lost.
+const queryInterval = time.Second
+
+// Firmata plots the pins of a board running StandardFirmata over a serial
+// port. Analog inputs are plotted as channels A0, A1, ... and digital
+// inputs as D2, D3, ..., so boards can be plotted without writing a
+// sketch.
+type Firmata struct {
+	mutex    sync.Mutex
+	port     *serial.SerialPort
+	pins     Pins
+	interval time.Duration
+	monitor  func(line datasources.RawLine)
+	// reporting are the pins the board was told to report when opened
+	reporting Pins
+	parser    parser
+	buff      []byte
+	pending   []byte
+	// digitalPorts holds the last state of each digital port, so a pin
+	// change report can be split into pins
+	digitalPorts map[int]int
+}
+
+// New creates a Firmata source talking to a board over port.
+func New(port *serial.SerialPort, pins Pins) *Firmata {
+	return &Firmata{
+		port:     port,
+		pins:     pins,
+		interval: DefaultSamplingInterval,
+	}
+}
+
+func (f *Firmata) SetPins(pins Pins) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.pins = pins
+}
+
+func (f *Firmata) SetInterval(interval time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.interval = interval
+}
+
+func (f *Firmata) SetMonitor(monitor func(line datasources.RawLine)) {
+	f.monitor = monitor
+}
+
+// Open opens the port, queries the board's capabilities and analog
+// mapping, checks the selected pins against them, then sets the sampling
+// interval and enables reporting for each pin.
+func (f *Firmata) Open(ctx context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.pins.Analog) == 0 && len(f.pins.Digital) == 0 {
+		return fmt.Errorf("no firmata pins selected")
+	}
+	err := f.port.CheckRawSettings("Firmata")
+	if err != nil {
+		return err
+	}
+	f.parser = parser{}
+	f.buff = make([]byte, 256)
+	f.pending = nil
+	f.digitalPorts = map[int]int{}
+	err = f.port.Open(ctx)
+	if err != nil {
+		return err
+	}
+	pinCapabilities, mapping, err := f.handshake(ctx)
+	if err != nil {
+		f.port.Close()
+		return err
+	}
+	commands, err := f.reportCommands(pinCapabilities, mapping)
+	if err != nil {
+		f.port.Close()
+		return err
+	}
+	err = f.port.Write(commands)
+	if err != nil {
+		f.port.Close()
+		return err
+	}
+	f.reporting = f.pins
+	return nil
+}
+
+// handshake queries the board until it has described its pins.
+func (f *Firmata) handshake(ctx context.Context) (capabilities, analogMapping, error) {
+	handshakeCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	var pinCapabilities capabilities
+	var mapping analogMapping
+	query := []byte{
+		reportVersion,
+		startSysex, reportFirmware, endSysex,
+		startSysex, capabilityQuery, endSysex,
+		startSysex, analogMappingQuery, endSysex,
+	}
+	for pinCapabilities == nil || mapping == nil {
+		err := f.port.Write(query)
+		if err != nil {
+			return nil, nil, err
+		}
+		queryCtx, cancelQuery := context.WithTimeout(handshakeCtx, queryInterval)
+		for pinCapabilities == nil || mapping == nil {
+			msg, err := f.readMessage(queryCtx)
+			if err != nil {
+				break
+			}
+			if msg.command != startSysex {
+				continue
+			}
+			switch msg.data[0] {
+			case capabilityResponse:
+				pinCapabilities = parseCapabilities(msg.data[1:])
+			case analogMappingResponse:
+				mapping = parseAnalogMapping(msg.data[1:])
+			}
+		}
+		cancelQuery()
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if handshakeCtx.Err() != nil {
+			return nil, nil, fmt.Errorf("board did not answer firmata queries, check it runs StandardFirmata at this baud")
+		}
+	}
+	return pinCapabilities, mapping, nil
+}
+
+// reportCommands returns the commands setting the sampling interval, the
+// pin modes and enabling reporting for the selected pins.
+func (f *Firmata) reportCommands(pinCapabilities capabilities, mapping analogMapping) ([]byte, error) {
+	interval := int(f.interval.Milliseconds())
+	commands := append([]byte{startSysex, samplingInterval}, encode7(interval)...)
+	commands = append(commands, endSysex)
+	for _, channel := range f.pins.Analog {
+		pin, ok := mapping[channel]
+		if !ok || !pinCapabilities.supports(pin, Analog) {
+			return nil, fmt.Errorf("board has no analog input %s", analogName(channel))
+		}
+		commands = append(commands, setPinMode, byte(pin), byte(Analog))
+		commands = append(commands, reportAnalog|byte(channel), 1)
+	}
+	reportedPorts := map[int]bool{}
+	for _, pin := range f.pins.Digital {
+		if !pinCapabilities.supports(pin, Input) {
+			return nil, fmt.Errorf("board pin %s can't be a digital input", digitalName(pin))
+		}
+		commands = append(commands, setPinMode, byte(pin), byte(Input))
+		port := pin / 8
+		if !reportedPorts[port] {
+			commands = append(commands, reportDigital|byte(port), 1)
+			reportedPorts[port] = true
+		}
+	}
+	return commands, nil
+}
+
+// readMessage returns the next message from the board, reporting it to
+// the monitor.
+func (f *Firmata) readMessage(ctx context.Context) (message, error) {
+	for {
+		for len(f.pending) > 0 {
+			b := f.pending[0]
+			f.pending = f.pending[1:]
+			msg, ok := f.parser.write(b)
+			if !ok {
+				continue
+			}
+			f.observe(msg)
+			return msg, nil
+		}
+		n, err := f.port.ReadBytes(ctx, f.buff)
+		if err != nil {
+			return message{}, err
+		}
+		f.pending = f.buff[:n]
+	}
+}
+
+// observe records the firmware and reports a message to the monitor.
+func (f *Firmata) observe(msg message) {
+	text := ""
+	switch msg.command {
+	case analogMessage:
+		text = fmt.Sprintf("analog %s = %d", analogName(int(msg.channel)), msg.value())
+	case digitalMessage:
+		text = fmt.Sprintf("digital port %d = %08b", msg.channel, msg.value())
+	case reportVersion:
+		text = fmt.Sprintf("protocol version %d.%d", msg.data[0], msg.data[1])
+	case startSysex:
+		switch msg.data[0] {
+		case reportFirmware:
+			if len(msg.data) >= 3 {
+				text = fmt.Sprintf("firmware %s %d.%d", decodeString(msg.data[3:]), msg.data[1], msg.data[2])
+			}
+		case stringData:
+			text = "string " + decodeString(msg.data[1:])
+		case capabilityResponse:
+			text = fmt.Sprintf("capabilities of %d pins", len(parseCapabilities(msg.data[1:])))
+		case analogMappingResponse:
+			text = fmt.Sprintf("analog mapping of %d channels", len(parseAnalogMapping(msg.data[1:])))
+		default:
+			text = fmt.Sprintf("sysex % X", msg.data)
+		}
+	default:
+		text = fmt.Sprintf("command %02X % X", msg.command|msg.channel, msg.data)
+	}
+	if f.monitor != nil {
+		f.monitor(datasources.RawLine{Received: time.Now(), Text: text})
+	}
+}
+
+// Read returns the samples of the next analog or digital report for a
+// selected pin.
+func (f *Firmata) Read(ctx context.Context) ([]datasources.Sample, error) {
+	for {
+		msg, err := f.readMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil, err
+			}
+			fmt.Println("failed to read firmata board", err)
+			return nil, err
+		}
+		data := f.samples(msg)
+		if len(data) == 0 {
+			continue
+		}
+		received := time.Now()
+		for i := range data {
+			data[i].Received = received
+		}
+		return data, nil
+	}
+}
+
+// samples turns a report into samples of the selected pins it covers.
+func (f *Firmata) samples(msg message) []datasources.Sample {
+	data := []datasources.Sample{}
+	switch msg.command {
+	case analogMessage:
+		for _, channel := range f.reporting.Analog {
+			if channel == int(msg.channel) {
+				data = append(data, datasources.Sample{Name: analogName(channel), Value: float32(msg.value())})
+			}
+		}
+	case digitalMessage:
+		port := int(msg.channel)
+		f.digitalPorts[port] = msg.value()
+		for _, pin := range f.reporting.Digital {
+			if pin/8 == port {
+				value := f.digitalPorts[port] >> (pin % 8) & 1
+				data = append(data, datasources.Sample{Name: digitalName(pin), Value: float32(value)})
+			}
+		}
+	}
+	return data
+}
+
+// Close stops the board reporting, so it is quiet when next opened, and
+// closes the port.
+func (f *Firmata) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	commands := []byte{}
+	for _, channel := range f.reporting.Analog {
+		commands = append(commands, reportAnalog|byte(channel), 0)
+	}
+	for _, pin := range f.reporting.Digital {
+		commands = append(commands, reportDigital|byte(pin/8), 0)
+	}
+	f.reporting = Pins{}
+	if len(commands) > 0 {
+		err := f.port.Write(commands)
+		if err != nil {
+			fmt.Println("failed to stop firmata reporting", err)
+		}
+	}
+	return f.port.Close()
+}
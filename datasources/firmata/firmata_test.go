@@ -0,0 +1,106 @@
+//go:build linux
+
+package firmata
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+	"github.com/taylorcoons/serial-plotter/internal/ptytest"
+)
+
+// serveBoard answers like a StandardFirmata board with digital pins 0 to
+// 3 and analog inputs A0 and A1 on pins 4 and 5, reporting A1 = 512 and
+// digital port 0 = 0b0100 once they are enabled.
+func serveBoard(master *os.File) {
+	p := parser{}
+	buff := make([]byte, 64)
+	for {
+		n, err := master.Read(buff)
+		if err != nil {
+			return
+		}
+		for _, b := range buff[:n] {
+			msg, ok := p.write(b)
+			if !ok {
+				continue
+			}
+			switch {
+			case msg.command == reportVersion:
+				master.Write([]byte{reportVersion, 2, 5})
+			case msg.command == startSysex && msg.data[0] == capabilityQuery:
+				response := []byte{startSysex, capabilityResponse}
+				for range 4 {
+					response = append(response, byte(Input), 1, byte(Output), 1, noAnalogChannel)
+				}
+				for range 2 {
+					response = append(response, byte(Input), 1, byte(Analog), 10, noAnalogChannel)
+				}
+				master.Write(append(response, endSysex))
+			case msg.command == startSysex && msg.data[0] == analogMappingQuery:
+				master.Write([]byte{startSysex, analogMappingResponse, 0x7f, 0x7f, 0x7f, 0x7f, 0, 1, endSysex})
+			case msg.command == reportAnalog && msg.channel == 1 && msg.data[0] == 1:
+				master.Write(append([]byte{analogMessage | 1}, encode7(512)...))
+			case msg.command == reportDigital && msg.channel == 0 && msg.data[0] == 1:
+				master.Write(append([]byte{digitalMessage}, encode7(0b0100)...))
+			}
+		}
+	}
+}
+
+func TestFirmataReporting(t *testing.T) {
+	master, path := ptytest.Open(t)
+	defer master.Close()
+	go serveBoard(master)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(serial.New(path, 57600), Pins{Analog: []int{1}, Digital: []int{2, 3}})
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	got := map[string]float32{}
+	for len(got) < 3 {
+		data, err := source.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sample := range data {
+			got[sample.Name] = sample.Value
+		}
+	}
+	want := map[string]float32{"A1": 512, "D2": 1, "D3": 0}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %v, want %v", name, got[name], value)
+		}
+	}
+}
+
+func TestFirmataMissingPin(t *testing.T) {
+	master, path := ptytest.Open(t)
+	defer master.Close()
+	go serveBoard(master)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lines := []datasources.RawLine{}
+	source := New(serial.New(path, 57600), Pins{Analog: []int{2}})
+	source.SetMonitor(func(line datasources.RawLine) {
+		lines = append(lines, line)
+	})
+	err := source.Open(ctx)
+	if err == nil {
+		source.Close()
+		t.Fatal("opened with an analog input the board doesn't have")
+	}
+	if len(lines) == 0 {
+		t.Error("handshake was not reported to the monitor")
+	}
+}
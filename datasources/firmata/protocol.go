@@ -0,0 +1,242 @@
+package firmata
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Firmata commands, the channel commands carry a pin, port or analog
+// channel in their low nibble.
+const (
+	digitalMessage = 0x90
+	reportAnalog   = 0xc0
+	reportDigital  = 0xd0
+	analogMessage  = 0xe0
+	startSysex     = 0xf0
+	setPinMode     = 0xf4
+	endSysex       = 0xf7
+	reportVersion  = 0xf9
+)
+
+// Firmata sysex commands.
+const (
+	analogMappingQuery    = 0x69
+	analogMappingResponse = 0x6a
+	capabilityQuery       = 0x6b
+	capabilityResponse    = 0x6c
+	stringData            = 0x71
+	reportFirmware        = 0x79
+	samplingInterval      = 0x7a
+)
+
+// PinMode is a mode a pin can be set to.
+type PinMode byte
+
+const (
+	Input  PinMode = 0x00
+	Output PinMode = 0x01
+	Analog PinMode = 0x02
+	PWM    PinMode = 0x03
+	Servo  PinMode = 0x04
+	Pullup PinMode = 0x0b
+)
+
+// noAnalogChannel marks a pin without an analog channel in the analog
+// mapping, and ends each pin's modes in the capability response.
+const noAnalogChannel = 0x7f
+
+// message is a complete Firmata message. Channel commands have their low
+// nibble moved to channel, sysex messages keep their sysex command as the
+// first byte of data.
+type message struct {
+	command byte
+	channel byte
+	data    []byte
+}
+
+// value returns a 14 bit value sent as two 7 bit bytes, low byte first.
+func (m message) value() int {
+	return int(m.data[0]) | int(m.data[1])<<7
+}
+
+// dataLength returns the number of data bytes following a command byte.
+func dataLength(command byte) int {
+	switch {
+	case command >= 0x80 && command < 0xc0, command >= 0xe0 && command < 0xf0:
+		return 2
+	case command >= 0xc0 && command < 0xe0:
+		return 1
+	case command == setPinMode, command == reportVersion:
+		return 2
+	}
+	return 0
+}
+
+// parser splits the bytes from a board into messages. Data bytes have the
+// high bit clear, so a command byte always starts a new message and
+// resynchronises the parser after a corrupt one.
+type parser struct {
+	command byte
+	sysex   bool
+	data    []byte
+}
+
+// write parses a byte, returning a message when it completes one.
+func (p *parser) write(b byte) (message, bool) {
+	if p.sysex {
+		if b == endSysex {
+			p.sysex = false
+			if len(p.data) == 0 {
+				return message{}, false
+			}
+			return message{command: startSysex, data: bytes.Clone(p.data)}, true
+		}
+		if b&0x80 == 0 {
+			p.data = append(p.data, b)
+			return message{}, false
+		}
+		p.sysex = false
+	}
+	if b&0x80 != 0 {
+		p.command = b
+		p.data = p.data[:0]
+		p.sysex = b == startSysex
+		return message{}, false
+	}
+	if p.command == 0 {
+		return message{}, false
+	}
+	p.data = append(p.data, b)
+	if len(p.data) < dataLength(p.command) {
+		return message{}, false
+	}
+	msg := message{command: p.command, data: bytes.Clone(p.data)}
+	if p.command < 0xf0 {
+		msg.command = p.command & 0xf0
+		msg.channel = p.command & 0x0f
+	}
+	// Boards may send running status, repeating data without the command
+	p.data = p.data[:0]
+	return msg, true
+}
+
+// encode7 splits a value into the two 7 bit bytes Firmata sends, low byte
+// first.
+func encode7(value int) []byte {
+	return []byte{byte(value & 0x7f), byte(value >> 7 & 0x7f)}
+}
+
+// decodeString decodes the 7 bit byte pairs of a string data or firmware
+// name.
+func decodeString(data []byte) string {
+	text := []byte{}
+	for i := 0; i+1 < len(data); i += 2 {
+		text = append(text, data[i]|data[i+1]<<7)
+	}
+	return string(text)
+}
+
+// capabilities are the modes each pin supports, indexed by pin.
+type capabilities [][]PinMode
+
+func parseCapabilities(data []byte) capabilities {
+	pins := capabilities{}
+	modes := []PinMode{}
+	for i := 0; i < len(data); i++ {
+		if data[i] == noAnalogChannel {
+			pins = append(pins, modes)
+			modes = []PinMode{}
+			continue
+		}
+		// Each mode is followed by its resolution
+		modes = append(modes, PinMode(data[i]))
+		i++
+	}
+	return pins
+}
+
+func (c capabilities) supports(pin int, mode PinMode) bool {
+	if pin < 0 || pin >= len(c) {
+		return false
+	}
+	for _, supported := range c[pin] {
+		if supported == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// analogMapping maps each analog channel to its pin.
+type analogMapping map[int]int
+
+func parseAnalogMapping(data []byte) analogMapping {
+	mapping := analogMapping{}
+	for pin, channel := range data {
+		if channel != noAnalogChannel {
+			mapping[int(channel)] = pin
+		}
+	}
+	return mapping
+}
+
+// Pins are the analog channels and digital pins to plot.
+type Pins struct {
+	Analog  []int
+	Digital []int
+}
+
+// ParsePins parses a comma or space separated list of analog channels,
+// written A0, A1, and digital pins, written D2 or 2.
+func ParsePins(value string) (Pins, error) {
+	pins := Pins{}
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	for _, field := range fields {
+		upper := strings.ToUpper(field)
+		analog := strings.HasPrefix(upper, "A")
+		digits := upper
+		if analog || strings.HasPrefix(upper, "D") {
+			digits = upper[1:]
+		}
+		number, err := strconv.Atoi(digits)
+		if err != nil || number < 0 || number > 127 {
+			return pins, fmt.Errorf("invalid firmata pin (%s), use A0 for analog inputs or D2 for digital pins", field)
+		}
+		if analog {
+			if number > 15 {
+				return pins, fmt.Errorf("firmata reports analog channels A0 to A15 (%s)", field)
+			}
+			pins.Analog = append(pins.Analog, number)
+		} else {
+			pins.Digital = append(pins.Digital, number)
+		}
+	}
+	if len(pins.Analog) == 0 && len(pins.Digital) == 0 {
+		return pins, fmt.Errorf("no firmata pins selected")
+	}
+	return pins, nil
+}
+
+// String returns the pins as ParsePins reads them.
+func (p Pins) String() string {
+	names := []string{}
+	for _, channel := range p.Analog {
+		names = append(names, analogName(channel))
+	}
+	for _, pin := range p.Digital {
+		names = append(names, digitalName(pin))
+	}
+	return strings.Join(names, ", ")
+}
+
+func analogName(channel int) string {
+	return "A" + strconv.Itoa(channel)
+}
+
+func digitalName(pin int) string {
+	return "D" + strconv.Itoa(pin)
+}
@@ -0,0 +1,112 @@
+package firmata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+func TestParser(t *testing.T) {
+	stream := []byte{
+		0x05,             // data before any command is ignored
+		0xe1, 0x7f, 0x07, // analog A1 = 1023
+		0x10, 0x00, // running status, analog A1 = 16
+		0x92, 0x04, // truncated by the next command
+		0x90, 0x05, 0x00, // digital port 0 = 0b101
+		0xf0, 0x79, 0x02, 0x05, 'S', 0x00, 'F', 0x00, 0xf7, // firmware SF 2.5
+		0xf9, 0x02, 0x05, // protocol version 2.5
+	}
+	want := []message{
+		{command: analogMessage, channel: 1, data: []byte{0x7f, 0x07}},
+		{command: analogMessage, channel: 1, data: []byte{0x10, 0x00}},
+		{command: digitalMessage, channel: 0, data: []byte{0x05, 0x00}},
+		{command: startSysex, data: []byte{reportFirmware, 0x02, 0x05, 'S', 0x00, 'F', 0x00}},
+		{command: reportVersion, data: []byte{0x02, 0x05}},
+	}
+	p := parser{}
+	got := []message{}
+	for _, b := range stream {
+		msg, ok := p.write(b)
+		if ok {
+			got = append(got, msg)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("messages = %v, want %v", got, want)
+	}
+	if got[0].value() != 1023 {
+		t.Errorf("value = %d, want 1023", got[0].value())
+	}
+	if name := decodeString(got[3].data[3:]); name != "SF" {
+		t.Errorf("firmware name = %q, want SF", name)
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	data := []byte{
+		0x7f,                         // pin 0 has no modes
+		0x00, 0x01, 0x01, 0x01, 0x7f, // pin 1 input and output
+		0x00, 0x01, 0x02, 0x0a, 0x7f, // pin 2 input and analog
+	}
+	pins := parseCapabilities(data)
+	if len(pins) != 3 {
+		t.Fatalf("pins = %v, want 3", pins)
+	}
+	if pins.supports(0, Input) || !pins.supports(1, Output) || !pins.supports(2, Analog) || pins.supports(2, Output) || pins.supports(3, Input) {
+		t.Errorf("capabilities = %v", pins)
+	}
+	mapping := parseAnalogMapping([]byte{0x7f, 0x7f, 0x00, 0x01})
+	if !reflect.DeepEqual(mapping, analogMapping{0: 2, 1: 3}) {
+		t.Errorf("analog mapping = %v", mapping)
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Pins
+		wantErr bool
+	}{
+		{value: "A0, A1, D2", want: Pins{Analog: []int{0, 1}, Digital: []int{2}}},
+		{value: "a3 7", want: Pins{Analog: []int{3}, Digital: []int{7}}},
+		{value: "", wantErr: true},
+		{value: "A16", wantErr: true},
+		{value: "AD1", wantErr: true},
+		{value: "D-1", wantErr: true},
+		{value: "B2", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParsePins(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParsePins(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParsePins(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFirmataRejectsTextSettings(t *testing.T) {
+	port := serial.New("/dev/null", 57600)
+	port.SetAutoBaud()
+	source := New(port, Pins{Analog: []int{0}})
+	err := source.Open(context.Background())
+	if err == nil {
+		source.Close()
+		t.Fatal("opened with auto baud")
+	}
+
+	port = serial.New("/dev/null", 57600)
+	config := port.Config()
+	config.FlowControl = serial.XONXOFFFlowControl
+	port.SetConfig(config)
+	source = New(port, Pins{Analog: []int{0}})
+	err = source.Open(context.Background())
+	if err == nil {
+		source.Close()
+		t.Fatal("opened with XON/XOFF")
+	}
+}
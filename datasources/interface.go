@@ -1,5 +1,43 @@
 package datasources
 
+import (
+	"context"
+	"time"
+)
+
+// Sample is a single reading of a named channel.
+type Sample struct {
+	Name  string
+	Value float32
+	// Received is when the sample was read, including the monotonic clock
+	// reading so it is unaffected by wall clock changes.
+	Received time.Time
+	// DeviceTime is the timestamp reported by the device on the same line,
+	// only meaningful when HasDeviceTime is set.
+	DeviceTime    time.Duration
+	HasDeviceTime bool
+}
+
+// RawLine is a line as it was received, before parsing, so the data a
+// device actually sends can be shown alongside the plot.
+type RawLine struct {
+	Received time.Time
+	Text     string
+	// Err is why the line could not be parsed, or nil if it parsed
+	Err error
+}
+
+// Monitored is implemented by data sources that can report each raw line
+// they receive. The monitor is called from the goroutine calling Read.
+type Monitored interface {
+	SetMonitor(monitor func(line RawLine))
+}
+
+// DataSourcer is a stream of data that is opened when plotting starts and
+// closed when it stops. Read blocks until data is available and returns
+// promptly with the context's error once the context is cancelled.
 type DataSourcer interface {
-	ReadSource() (float32, error)
+	Open(ctx context.Context) error
+	Read(ctx context.Context) ([]Sample, error)
+	Close() error
 }
@@ -0,0 +1,184 @@
+// Package jsonmap turns JSON documents into samples, either every number in
+// the document or the values at a mapping of JSON paths to channel names.
+package jsonmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+// Decode parses a JSON document, keeping numbers as json.Number.
+func Decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// Number converts a JSON value to a number, booleans being 0 or 1.
+func Number(value any) (float64, bool) {
+	switch value := value.(type) {
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Flatten returns a sample for every number in document named by its path,
+// e.g. {"env":{"temp":21.5},"axes":[1,2]} gives env.temp, axes.0 and
+// axes.1. Object fields are visited in sorted order.
+func Flatten(document any) []datasources.Sample {
+	samples := []datasources.Sample{}
+	flatten("", document, &samples)
+	return samples
+}
+
+func flatten(name string, value any, samples *[]datasources.Sample) {
+	if number, ok := Number(value); ok {
+		*samples = append(*samples, datasources.Sample{Name: name, Value: float32(number)})
+		return
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			flatten(join(name, key), value[key], samples)
+		}
+	case []any:
+		for i, item := range value {
+			flatten(join(name, strconv.Itoa(i)), item, samples)
+		}
+	}
+}
+
+func join(name string, key string) string {
+	if name == "" {
+		return key
+	}
+	return name + "." + key
+}
+
+// Path is the location of a value in a JSON document.
+type Path []string
+
+// ParsePath parses a path such as $.env.temp, env.temp or axes[0]. The
+// leading $ is optional.
+func ParsePath(value string) (Path, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "$"), ".")
+	value = strings.ReplaceAll(strings.ReplaceAll(value, "[", "."), "]", "")
+	if value == "" {
+		return nil, fmt.Errorf("empty json path")
+	}
+	path := Path(strings.Split(value, "."))
+	for _, segment := range path {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid json path (%s)", value)
+		}
+	}
+	return path, nil
+}
+
+// String returns the path in the form Flatten names values.
+func (p Path) String() string {
+	return strings.Join(p, ".")
+}
+
+// Lookup returns the value at the path in document.
+func (p Path) Lookup(document any) (any, bool) {
+	value := document
+	for _, segment := range p {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Field maps the value at a path to a named channel.
+type Field struct {
+	Name string
+	Path Path
+}
+
+// Mapping picks channels out of each document.
+type Mapping []Field
+
+// ParseMapping parses comma separated name=path pairs, e.g.
+// "temp=$.env.temp, rh=$.rh". A path without a name plots as a channel
+// named after the path.
+func ParseMapping(value string) (Mapping, error) {
+	mapping := Mapping{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, pathValue, named := strings.Cut(entry, "=")
+		if !named {
+			pathValue = entry
+		}
+		path, err := ParsePath(pathValue)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimSpace(name)
+		if !named || name == "" {
+			name = path.String()
+		}
+		mapping = append(mapping, Field{Name: name, Path: path})
+	}
+	return mapping, nil
+}
+
+// Apply returns a sample for each mapped path holding a number in document.
+func (m Mapping) Apply(document any) []datasources.Sample {
+	samples := []datasources.Sample{}
+	for _, field := range m {
+		value, ok := field.Path.Lookup(document)
+		if !ok {
+			continue
+		}
+		number, ok := Number(value)
+		if !ok {
+			continue
+		}
+		samples = append(samples, datasources.Sample{Name: field.Name, Value: float32(number)})
+	}
+	return samples
+}
@@ -0,0 +1,70 @@
+package jsonmap
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Path
+		wantErr bool
+	}{
+		{value: "$.env.temp", want: Path{"env", "temp"}},
+		{value: "env.temp", want: Path{"env", "temp"}},
+		{value: "$.axes[1]", want: Path{"axes", "1"}},
+		{value: "$", wantErr: true},
+		{value: "env..temp", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParsePath(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParsePath(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("ParsePath(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestMappingApply(t *testing.T) {
+	document, err := Decode([]byte(`{"env": {"temp": 21.5}, "axes": [1, 2], "on": true, "name": "rig"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := ParseMapping("temp=$.env.temp, y=$.axes[1], on, name, missing=$.nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := mapping.Apply(document)
+	want := []struct {
+		name  string
+		value float32
+	}{{"temp", 21.5}, {"y", 2}, {"on", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("Apply = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Name != want[i].name || got[i].Value != want[i].value {
+			t.Errorf("Apply = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	document, err := Decode([]byte(`{"env": {"temp": 21.5}, "axes": [1, 2], "name": "rig"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Flatten(document)
+	names := []string{}
+	for _, sample := range got {
+		names = append(names, sample.Name)
+	}
+	want := []string{"axes.0", "axes.1", "env.temp"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Flatten names = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,86 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// exceptionName describes the standard Modbus exception codes.
+var exceptionName = map[byte]string{
+	1:  "illegal function",
+	2:  "illegal data address",
+	3:  "illegal data value",
+	4:  "slave device failure",
+	6:  "slave device busy",
+	10: "gateway path unavailable",
+	11: "gateway target failed to respond",
+}
+
+// Exception is an exception response from a slave.
+type Exception struct {
+	Function Function
+	Code     byte
+}
+
+func (e *Exception) Error() string {
+	name, ok := exceptionName[e.Code]
+	if !ok {
+		name = fmt.Sprintf("code %d", e.Code)
+	}
+	return fmt.Sprintf("modbus exception reading %s registers: %s", e.Function, name)
+}
+
+// readRequest returns the PDU reading count registers from address.
+func readRequest(function Function, address uint16, count uint16) []byte {
+	pdu := []byte{byte(function), 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], count)
+	return pdu
+}
+
+// readResponse checks the PDU of a read response and returns its data.
+func readResponse(function Function, count uint16, pdu []byte) ([]byte, error) {
+	if len(pdu) >= 2 && pdu[0] == byte(function)|0x80 {
+		return nil, &Exception{Function: function, Code: pdu[1]}
+	}
+	if len(pdu) < 2 || pdu[0] != byte(function) {
+		return nil, serial.NewParseError(fmt.Sprintf("unexpected modbus response % x", pdu))
+	}
+	if int(pdu[1]) != int(count)*2 || len(pdu) != 2+int(pdu[1]) {
+		return nil, serial.NewParseError(fmt.Sprintf("modbus response has %d bytes, want %d", len(pdu)-2, count*2))
+	}
+	return pdu[2:], nil
+}
+
+// crc16 is the CRC appended to Modbus RTU frames, sent low byte first.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// rtuFrame wraps a PDU for a slave with its CRC.
+func rtuFrame(slaveID byte, pdu []byte) []byte {
+	frame := append([]byte{slaveID}, pdu...)
+	return binary.LittleEndian.AppendUint16(frame, crc16(frame))
+}
+
+// tcpFrame wraps a PDU with the MBAP header used by Modbus TCP.
+func tcpFrame(transactionID uint16, slaveID byte, pdu []byte) []byte {
+	frame := make([]byte, 7, 7+len(pdu))
+	binary.BigEndian.PutUint16(frame[0:], transactionID)
+	binary.BigEndian.PutUint16(frame[4:], uint16(len(pdu)+1))
+	frame[6] = slaveID
+	return append(frame, pdu...)
+}
@@ -0,0 +1,202 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+const DefaultPollInterval = time.Second
+
+// Transport is how the plotter reaches the Modbus slaves.
+type Transport int
+
+const (
+	RTU Transport = iota
+	TCP
+)
+
+var transportName = map[Transport]string{
+	RTU: "RTU",
+	TCP: "TCP",
+}
+
+func (t Transport) String() string {
+	return transportName[t]
+}
+
+func TransportOptions() []string {
+	return []string{
+		RTU.String(),
+		TCP.String(),
+	}
+}
+
+func ParseTransport(value string) (Transport, error) {
+	for transport, name := range transportName {
+		if name == value {
+			return transport, nil
+		}
+	}
+	return RTU, fmt.Errorf("unknown modbus transport (%s)", value)
+}
+
+// Modbus polls registers from instruments every poll interval, over a
+// serial port with Modbus RTU or over the network with Modbus TCP. Each
+// register is plotted as a named channel. A register that a slave doesn't
+// answer, or answers with an exception, is skipped for that poll.
+type Modbus struct {
+	mutex     sync.Mutex
+	transport Transport
+	port      *serial.SerialPort
+	address   string
+	registers []Register
+	interval  time.Duration
+	monitor   func(line datasources.RawLine)
+	active    transport
+	next      time.Time
+}
+
+// New creates a Modbus source polling over port with RTU, or address, a
+// host:port, with TCP.
+func New(transport Transport, port *serial.SerialPort, address string) *Modbus {
+	return &Modbus{
+		transport: transport,
+		port:      port,
+		address:   address,
+		interval:  DefaultPollInterval,
+	}
+}
+
+func (m *Modbus) SetTransport(transport Transport) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.transport = transport
+}
+
+func (m *Modbus) SetAddress(address string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.address = address
+}
+
+func (m *Modbus) SetRegisters(registers []Register) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.registers = registers
+}
+
+func (m *Modbus) SetInterval(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.interval = interval
+}
+
+func (m *Modbus) SetMonitor(monitor func(line datasources.RawLine)) {
+	m.monitor = monitor
+}
+
+func (m *Modbus) Open(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.registers) == 0 {
+		return fmt.Errorf("no modbus registers configured")
+	}
+	switch m.transport {
+	case RTU:
+		m.active = &rtu{port: m.port}
+	case TCP:
+		m.active = &tcp{address: m.address}
+	}
+	m.next = time.Now()
+	return m.active.open(ctx)
+}
+
+func (m *Modbus) Read(ctx context.Context) ([]datasources.Sample, error) {
+	m.mutex.Lock()
+	registers, interval := m.registers, m.interval
+	m.mutex.Unlock()
+	err := sleepUntil(ctx, m.next)
+	if err != nil {
+		return nil, err
+	}
+	received := time.Now()
+	m.next = m.next.Add(interval)
+	if m.next.Before(received) {
+		// Polling fell behind, skip the missed polls
+		m.next = received.Add(interval)
+	}
+	data := []datasources.Sample{}
+	for _, register := range registers {
+		value, err := m.poll(ctx, register, received)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var parseError *serial.ParseError
+		var exception *Exception
+		if errors.As(err, &parseError) || errors.As(err, &exception) {
+			fmt.Println("failed to poll modbus register", register.Name, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, datasources.Sample{Name: register.Name, Value: float32(value), Received: received})
+	}
+	if len(data) == 0 {
+		return nil, serial.NewParseError("no modbus registers responded")
+	}
+	return data, nil
+}
+
+// poll reads a register and reports it to the monitor.
+func (m *Modbus) poll(ctx context.Context, register Register, received time.Time) (float64, error) {
+	count := uint16(register.DataType.Words())
+	response, err := m.active.request(ctx, register.SlaveID, readRequest(register.Function, register.Address, count))
+	var data []byte
+	if err == nil {
+		data, err = readResponse(register.Function, count, response)
+	}
+	text := fmt.Sprintf("%s: slave %d %s %d", register.Name, register.SlaveID, register.Function, register.Address)
+	if err != nil {
+		if m.monitor != nil {
+			m.monitor(datasources.RawLine{Received: received, Text: text + ": " + err.Error(), Err: err})
+		}
+		return 0, err
+	}
+	value := register.decode(data)
+	if m.monitor != nil {
+		m.monitor(datasources.RawLine{Received: received, Text: fmt.Sprintf("%s = % X (%g)", text, data, value)})
+	}
+	return value, nil
+}
+
+func sleepUntil(ctx context.Context, deadline time.Time) error {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (m *Modbus) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.active == nil {
+		return nil
+	}
+	err := m.active.close()
+	m.active = nil
+	if err != nil {
+		fmt.Println("failed to close modbus source", err)
+	}
+	return err
+}
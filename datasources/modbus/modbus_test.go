@@ -0,0 +1,180 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+func TestParseRegister(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Register
+		wantErr bool
+	}{
+		{
+			value: "name=temp address=100",
+			want:  Register{Name: "temp", SlaveID: 1, Function: HoldingRegisters, Address: 100, DataType: Int16, WordOrder: HighWordFirst, Scale: 1},
+		},
+		{
+			value: "name=flow slave=7 function=input address=0x10 type=float32 order=CDAB scale=0.1",
+			want:  Register{Name: "flow", SlaveID: 7, Function: InputRegisters, Address: 16, DataType: Float32, WordOrder: LowWordFirst, Scale: 0.1},
+		},
+		{
+			value: "name=count function=3 address=2 type=uint32",
+			want:  Register{Name: "count", SlaveID: 1, Function: HoldingRegisters, Address: 2, DataType: Uint32, WordOrder: HighWordFirst, Scale: 1},
+		},
+		{value: "name=temp", wantErr: true},
+		{value: "address=100", wantErr: true},
+		{value: "name=temp address=100 type=int64", wantErr: true},
+		{value: "name=temp address=100 slave=300", wantErr: true},
+		{value: "name=temp address=100 colour=red", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseRegister(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseRegister(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && got != test.want {
+			t.Errorf("ParseRegister(%q) = %+v, want %+v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestRegisterDecode(t *testing.T) {
+	float := make([]byte, 4)
+	binary.BigEndian.PutUint32(float, math.Float32bits(21.5))
+	tests := []struct {
+		register Register
+		data     []byte
+		want     float64
+	}{
+		{register: Register{DataType: Int16, Scale: 1}, data: []byte{0xff, 0xfe}, want: -2},
+		{register: Register{DataType: Uint16, Scale: 0.5}, data: []byte{0xff, 0xfe}, want: 32767},
+		{register: Register{DataType: Int32, Scale: 1}, data: []byte{0xff, 0xff, 0xff, 0xfd}, want: -3},
+		{register: Register{DataType: Uint32, Scale: 1}, data: []byte{0x00, 0x01, 0x00, 0x02}, want: 65538},
+		{register: Register{DataType: Uint32, WordOrder: LowWordFirst, Scale: 1}, data: []byte{0x00, 0x02, 0x00, 0x01}, want: 65538},
+		{register: Register{DataType: Float32, Scale: 1}, data: float, want: 21.5},
+		{register: Register{DataType: Float32, WordOrder: LowWordFirst, Scale: 10}, data: []byte{float[2], float[3], float[0], float[1]}, want: 215},
+	}
+	for _, test := range tests {
+		got := test.register.decode(test.data)
+		if got != test.want {
+			t.Errorf("%s %s decode(% x) = %g, want %g", test.register.DataType, test.register.WordOrder, test.data, got, test.want)
+		}
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	frame := rtuFrame(1, readRequest(HoldingRegisters, 0, 10))
+	want := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a, 0xc5, 0xcd}
+	if string(frame) != string(want) {
+		t.Errorf("rtuFrame = % x, want % x", frame, want)
+	}
+}
+
+func TestRTUSync(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want []byte
+	}{
+		{data: []byte{0x03, 0x03, 0x02}, want: []byte{0x03, 0x03, 0x02}},
+		{data: []byte{0x03, 0x83, 0x02}, want: []byte{0x03, 0x83, 0x02}},
+		// A late reply from another slave and noise before the response
+		{data: []byte{0x04, 0x03, 0x02, 0x00, 0x03, 0x03, 0x02}, want: []byte{0x03, 0x03, 0x02}},
+		// A late reply from the same slave to another function
+		{data: []byte{0x03, 0x04, 0x03, 0x03}, want: []byte{0x03, 0x03}},
+		{data: []byte{0xff, 0x03}, want: []byte{0x03}},
+		{data: []byte{0xff, 0x00}, want: []byte{}},
+	}
+	for _, test := range tests {
+		got := rtuSync(test.data, 3, byte(HoldingRegisters))
+		if string(got) != string(test.want) {
+			t.Errorf("rtuSync(% x) = % x, want % x", test.data, got, test.want)
+		}
+	}
+}
+
+func checkPoll(t *testing.T, got []datasources.Sample, want map[string]float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Read = %+v, want %v", got, want)
+	}
+	for _, sample := range got {
+		if value, ok := want[sample.Name]; !ok || value != sample.Value {
+			t.Fatalf("Read = %+v, want %v", got, want)
+		}
+	}
+}
+
+func TestTCPPolling(t *testing.T) {
+	simulator := newSlave()
+	bits := math.Float32bits(21.5)
+	simulator.set(1, HoldingRegisters, 100, uint16(bits), uint16(bits>>16))
+	simulator.set(2, InputRegisters, 5, 0xfffe)
+	registers, err := ParseRegisters(`
+# temperature probe
+name=temp address=100 type=float32 order=CDAB
+name=level slave=2 function=input address=5 scale=0.5
+name=missing address=200
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(TCP, nil, simulator.serveTCP(t))
+	source.SetRegisters(registers)
+	source.SetInterval(10 * time.Millisecond)
+	err = source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	lines := []datasources.RawLine{}
+	source.SetMonitor(func(line datasources.RawLine) {
+		lines = append(lines, line)
+	})
+	for range 2 {
+		data, err := source.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The missing register's exception skips it
+		checkPoll(t, data, map[string]float32{"temp": 21.5, "level": -1})
+	}
+	if len(lines) != 6 || lines[2].Err == nil {
+		t.Errorf("monitor lines = %+v, want 6 with the third failed", lines)
+	}
+}
+
+func TestRTURejectsTextSettings(t *testing.T) {
+	port := serial.New("/dev/null", 9600)
+	port.SetAutoBaud()
+	source := New(RTU, port, "")
+	source.SetRegisters([]Register{{Name: "count", SlaveID: 3, Function: HoldingRegisters, DataType: Uint16, Scale: 1}})
+	err := source.Open(context.Background())
+	if err == nil {
+		source.Close()
+		t.Fatal("opened with auto baud")
+	}
+
+	port = serial.New("/dev/null", 9600)
+	config := port.Config()
+	config.FlowControl = serial.XONXOFFFlowControl
+	port.SetConfig(config)
+	source = New(RTU, port, "")
+	source.SetRegisters([]Register{{Name: "count", SlaveID: 3, Function: HoldingRegisters, DataType: Uint16, Scale: 1}})
+	err = source.Open(context.Background())
+	if err == nil {
+		source.Close()
+		t.Fatal("opened with XON/XOFF")
+	}
+}
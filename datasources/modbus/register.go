@@ -0,0 +1,210 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Function is the Modbus function code used to read a register.
+type Function byte
+
+const (
+	HoldingRegisters Function = 3
+	InputRegisters   Function = 4
+)
+
+var functionName = map[Function]string{
+	HoldingRegisters: "holding",
+	InputRegisters:   "input",
+}
+
+func (f Function) String() string {
+	return functionName[f]
+}
+
+// ParseFunction accepts holding or input, or the function code 3 or 4.
+func ParseFunction(value string) (Function, error) {
+	for function, name := range functionName {
+		if strings.EqualFold(name, value) || strconv.Itoa(int(function)) == value {
+			return function, nil
+		}
+	}
+	return HoldingRegisters, fmt.Errorf("unknown modbus function (%s)", value)
+}
+
+// DataType is how the words of a register are interpreted.
+type DataType int
+
+const (
+	Int16 DataType = iota
+	Uint16
+	Int32
+	Uint32
+	Float32
+)
+
+var dataTypeName = map[DataType]string{
+	Int16:   "int16",
+	Uint16:  "uint16",
+	Int32:   "int32",
+	Uint32:  "uint32",
+	Float32: "float32",
+}
+
+func (d DataType) String() string {
+	return dataTypeName[d]
+}
+
+// Words returns the number of 16 bit registers the type spans.
+func (d DataType) Words() int {
+	switch d {
+	case Int32, Uint32, Float32:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func ParseDataType(value string) (DataType, error) {
+	for dataType, name := range dataTypeName {
+		if strings.EqualFold(name, value) {
+			return dataType, nil
+		}
+	}
+	return Int16, fmt.Errorf("unknown modbus data type (%s)", value)
+}
+
+// WordOrder is the order of the words of a 32 bit value. Bytes within a
+// word are always big endian.
+type WordOrder int
+
+const (
+	// HighWordFirst is ABCD order, the Modbus convention
+	HighWordFirst WordOrder = iota
+	// LowWordFirst is CDAB order, used by many instruments
+	LowWordFirst
+)
+
+var wordOrderName = map[WordOrder]string{
+	HighWordFirst: "ABCD",
+	LowWordFirst:  "CDAB",
+}
+
+func (w WordOrder) String() string {
+	return wordOrderName[w]
+}
+
+func ParseWordOrder(value string) (WordOrder, error) {
+	for wordOrder, name := range wordOrderName {
+		if strings.EqualFold(name, value) {
+			return wordOrder, nil
+		}
+	}
+	return HighWordFirst, fmt.Errorf("unknown modbus word order (%s)", value)
+}
+
+// Register is a value polled from a slave and plotted as a named channel.
+type Register struct {
+	Name      string
+	SlaveID   byte
+	Function  Function
+	Address   uint16
+	DataType  DataType
+	WordOrder WordOrder
+	Scale     float64
+}
+
+// ParseRegister parses a register written as key=value fields, e.g.
+// "name=temp slave=1 function=input address=100 type=float32 order=CDAB
+// scale=0.1". Only the name and address are required, the slave defaults
+// to 1, the function to holding, the type to int16, the order to ABCD and
+// the scale to 1.
+func ParseRegister(value string) (Register, error) {
+	register := Register{
+		SlaveID:   1,
+		Function:  HoldingRegisters,
+		DataType:  Int16,
+		WordOrder: HighWordFirst,
+		Scale:     1,
+	}
+	hasAddress := false
+	for _, field := range strings.Fields(value) {
+		key, fieldValue, ok := strings.Cut(field, "=")
+		if !ok {
+			return register, fmt.Errorf("modbus register field must be key=value (%s)", field)
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "name":
+			register.Name = fieldValue
+		case "slave":
+			var slave uint64
+			slave, err = strconv.ParseUint(fieldValue, 0, 8)
+			register.SlaveID = byte(slave)
+		case "function":
+			register.Function, err = ParseFunction(fieldValue)
+		case "address":
+			var address uint64
+			address, err = strconv.ParseUint(fieldValue, 0, 16)
+			register.Address = uint16(address)
+			hasAddress = true
+		case "type":
+			register.DataType, err = ParseDataType(fieldValue)
+		case "order":
+			register.WordOrder, err = ParseWordOrder(fieldValue)
+		case "scale":
+			register.Scale, err = strconv.ParseFloat(fieldValue, 64)
+		default:
+			err = fmt.Errorf("unknown modbus register field (%s)", key)
+		}
+		if err != nil {
+			return register, fmt.Errorf("invalid modbus register %s: %w", field, err)
+		}
+	}
+	if register.Name == "" || !hasAddress {
+		return register, fmt.Errorf("modbus register needs a name and address (%s)", value)
+	}
+	return register, nil
+}
+
+// ParseRegisters parses one register per line, skipping blank lines and
+// lines starting with #.
+func ParseRegisters(value string) ([]Register, error) {
+	registers := []Register{}
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		register, err := ParseRegister(line)
+		if err != nil {
+			return nil, err
+		}
+		registers = append(registers, register)
+	}
+	return registers, nil
+}
+
+// decode converts the bytes of a register's words to its scaled value.
+func (r Register) decode(data []byte) float64 {
+	if r.DataType.Words() == 2 && r.WordOrder == LowWordFirst {
+		data = []byte{data[2], data[3], data[0], data[1]}
+	}
+	var value float64
+	switch r.DataType {
+	case Int16:
+		value = float64(int16(binary.BigEndian.Uint16(data)))
+	case Uint16:
+		value = float64(binary.BigEndian.Uint16(data))
+	case Int32:
+		value = float64(int32(binary.BigEndian.Uint32(data)))
+	case Uint32:
+		value = float64(binary.BigEndian.Uint32(data))
+	case Float32:
+		value = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	}
+	return value * r.Scale
+}
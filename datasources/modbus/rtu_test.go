@@ -0,0 +1,60 @@
+//go:build linux
+
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+	"github.com/taylorcoons/serial-plotter/internal/ptytest"
+)
+
+// serveRTU answers Modbus RTU requests written to the pty master.
+func (s *slave) serveRTU(master *os.File) {
+	buff := make([]byte, 8)
+	for {
+		// Every read request is 8 bytes
+		n := 0
+		for n < len(buff) {
+			read, err := master.Read(buff[n:])
+			if err != nil {
+				return
+			}
+			n += read
+		}
+		if binary.LittleEndian.Uint16(buff[6:]) != crc16(buff[:6]) {
+			continue
+		}
+		master.Write(rtuFrame(buff[0], s.handle(buff[0], buff[1:6])))
+	}
+}
+
+func TestRTUPolling(t *testing.T) {
+	master, path := ptytest.Open(t)
+	defer master.Close()
+	simulator := newSlave()
+	simulator.set(3, HoldingRegisters, 0, 0x0001, 0x0002)
+	go simulator.serveRTU(master)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(RTU, serial.New(path, 9600), "")
+	source.SetRegisters([]Register{
+		{Name: "count", SlaveID: 3, Function: HoldingRegisters, Address: 0, DataType: Uint32, Scale: 1},
+		{Name: "absent", SlaveID: 4, Function: HoldingRegisters, Address: 0, DataType: Int16, Scale: 1},
+	})
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	data, err := source.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPoll(t, data, map[string]float32{"count": 65538})
+}
@@ -0,0 +1,79 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// slave simulates the registers of Modbus slaves for tests.
+type slave struct {
+	registers map[byte]map[Function]map[uint16]uint16
+}
+
+func newSlave() *slave {
+	return &slave{registers: map[byte]map[Function]map[uint16]uint16{}}
+}
+
+func (s *slave) set(slaveID byte, function Function, address uint16, words ...uint16) {
+	if s.registers[slaveID] == nil {
+		s.registers[slaveID] = map[Function]map[uint16]uint16{}
+	}
+	if s.registers[slaveID][function] == nil {
+		s.registers[slaveID][function] = map[uint16]uint16{}
+	}
+	for i, word := range words {
+		s.registers[slaveID][function][address+uint16(i)] = word
+	}
+}
+
+// handle answers a request PDU, returning an illegal data address exception
+// for registers that don't exist.
+func (s *slave) handle(slaveID byte, pdu []byte) []byte {
+	function := Function(pdu[0])
+	address := binary.BigEndian.Uint16(pdu[1:])
+	count := binary.BigEndian.Uint16(pdu[3:])
+	response := []byte{byte(function), byte(count * 2)}
+	for i := range count {
+		word, ok := s.registers[slaveID][function][address+i]
+		if !ok {
+			return []byte{byte(function) | 0x80, 2}
+		}
+		response = binary.BigEndian.AppendUint16(response, word)
+	}
+	return response
+}
+
+// serveTCP answers Modbus TCP requests until the listener is closed.
+func (s *slave) serveTCP(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					header := make([]byte, 7)
+					if _, err := io.ReadFull(conn, header); err != nil {
+						return
+					}
+					pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+					if _, err := io.ReadFull(conn, pdu); err != nil {
+						return
+					}
+					transactionID := binary.BigEndian.Uint16(header)
+					conn.Write(tcpFrame(transactionID, header[6], s.handle(header[6], pdu)))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
@@ -0,0 +1,190 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// responseTimeout is how long to wait for a slave to answer a request.
+const responseTimeout = time.Second
+
+// transport sends a request PDU to a slave and returns the response PDU.
+// Timeouts and corrupt frames are returned as parse errors, as the next
+// poll can succeed, while other errors mean the connection is lost.
+type transport interface {
+	open(ctx context.Context) error
+	request(ctx context.Context, slaveID byte, pdu []byte) ([]byte, error)
+	close() error
+}
+
+// rtu speaks Modbus RTU over a serial port.
+type rtu struct {
+	port *serial.SerialPort
+	buff []byte
+}
+
+func (r *rtu) open(ctx context.Context) error {
+	err := r.port.CheckRawSettings("Modbus RTU")
+	if err != nil {
+		return err
+	}
+	r.buff = make([]byte, 256)
+	return r.port.Open(ctx)
+}
+
+func (r *rtu) request(ctx context.Context, slaveID byte, pdu []byte) ([]byte, error) {
+	// A reply arriving after its timeout, or line noise, would otherwise be
+	// taken as the start of this response
+	err := r.port.ResetInputBuffer()
+	if err != nil {
+		return nil, err
+	}
+	err = r.port.Write(rtuFrame(slaveID, pdu))
+	if err != nil {
+		return nil, err
+	}
+	responseCtx, cancel := context.WithTimeout(ctx, responseTimeout)
+	defer cancel()
+	frame := []byte{}
+	for {
+		frame = rtuSync(frame, slaveID, pdu[0])
+		length := rtuLength(frame)
+		if length > 0 && len(frame) >= length {
+			// Anything after the frame is stale
+			frame = frame[:length]
+			break
+		}
+		n, err := r.port.ReadBytes(responseCtx, r.buff)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return nil, serial.NewParseError(fmt.Sprintf("modbus slave %d did not respond", slaveID))
+			}
+			return nil, err
+		}
+		frame = append(frame, r.buff[:n]...)
+	}
+	crc := binary.LittleEndian.Uint16(frame[len(frame)-2:])
+	if crc != crc16(frame[:len(frame)-2]) {
+		return nil, serial.NewParseError(fmt.Sprintf("modbus response from slave %d failed its crc", slaveID))
+	}
+	return frame[1 : len(frame)-2], nil
+}
+
+// rtuSync drops bytes from the start of data until it starts with a
+// response from the slave to the function, or could still be one.
+func rtuSync(data []byte, slaveID byte, function byte) []byte {
+	for len(data) > 0 {
+		if data[0] == slaveID && (len(data) < 2 || data[1]&0x7f == function) {
+			return data
+		}
+		data = data[1:]
+	}
+	return data
+}
+
+// rtuLength returns the length of the read response frame at the start of
+// data, or 0 if not enough has arrived to tell.
+func rtuLength(data []byte) int {
+	if len(data) < 2 {
+		return 0
+	}
+	if data[1]&0x80 != 0 {
+		// Slave, function, exception code and crc
+		return 5
+	}
+	if len(data) < 3 {
+		return 0
+	}
+	// Slave, function, byte count, data and crc
+	return 3 + int(data[2]) + 2
+}
+
+func (r *rtu) close() error {
+	return r.port.Close()
+}
+
+// tcp speaks Modbus TCP, reconnecting after a request fails.
+type tcp struct {
+	address       string
+	conn          net.Conn
+	transactionID uint16
+}
+
+func (t *tcp) open(ctx context.Context) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	return nil
+}
+
+func (t *tcp) request(ctx context.Context, slaveID byte, pdu []byte) ([]byte, error) {
+	if t.conn == nil {
+		err := t.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	response, err := t.exchange(ctx, slaveID, pdu)
+	if err != nil {
+		// The response may still arrive, so start again on a new connection
+		t.close()
+		if ctx.Err() == nil && errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, serial.NewParseError(fmt.Sprintf("modbus slave %d did not respond", slaveID))
+		}
+	}
+	return response, err
+}
+
+func (t *tcp) exchange(ctx context.Context, slaveID byte, pdu []byte) ([]byte, error) {
+	err := t.conn.SetDeadline(time.Now().Add(responseTimeout))
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		t.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+	t.transactionID++
+	_, err = t.conn.Write(tcpFrame(t.transactionID, slaveID, pdu))
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 7)
+	_, err = io.ReadFull(t.conn, header)
+	if err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[4:]))
+	if length < 2 || length > 254 {
+		return nil, serial.NewParseError(fmt.Sprintf("invalid modbus tcp length %d", length))
+	}
+	response := make([]byte, length-1)
+	_, err = io.ReadFull(t.conn, response)
+	if err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(header[0:]) != t.transactionID {
+		return nil, serial.NewParseError("modbus tcp response to another request")
+	}
+	return response, nil
+}
+
+func (t *tcp) close() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
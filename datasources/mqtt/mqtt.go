@@ -0,0 +1,238 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+const (
+	connectTimeout    = 10 * time.Second
+	disconnectQuiesce = 250
+)
+
+func QoSOptions() []string {
+	return []string{
+		"0",
+		"1",
+		"2",
+	}
+}
+
+func ParseQoS(value string) (byte, error) {
+	qos, err := strconv.Atoi(value)
+	if err != nil || qos < 0 || qos > 2 {
+		return 0, fmt.Errorf("invalid qos (%s)", value)
+	}
+	return byte(qos), nil
+}
+
+// ParseTopics splits a comma separated list of topic filters.
+func ParseTopics(value string) []string {
+	topics := []string{}
+	for _, topic := range strings.Split(value, ",") {
+		topic = strings.TrimSpace(topic)
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// message is a publish received on a subscribed topic.
+type message struct {
+	topic    string
+	payload  []byte
+	received time.Time
+}
+
+// MQTT subscribes to topic filters on a broker and plots the messages
+// published to them, see decodePayload for how payloads map to channels.
+// The client reconnects and resubscribes if the broker goes away, and the
+// gap shows in the plot.
+type MQTT struct {
+	mutex    sync.Mutex
+	broker   string
+	topics   []string
+	qos      byte
+	username string
+	password string
+	monitor  func(line datasources.RawLine)
+	client   paho.Client
+	messages chan message
+	done     chan struct{}
+	// channels are the names plotted so far, given a gap after a reconnect
+	channels     map[string]bool
+	disconnected bool
+	gap          bool
+}
+
+// New creates a subscriber for the topic filters on broker, a URL such as
+// tcp://localhost:1883.
+func New(broker string, topics []string, qos byte) *MQTT {
+	return &MQTT{
+		broker: broker,
+		topics: topics,
+		qos:    qos,
+	}
+}
+
+func (m *MQTT) SetBroker(broker string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.broker = broker
+}
+
+func (m *MQTT) SetTopics(topics []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.topics = topics
+}
+
+func (m *MQTT) SetQoS(qos byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.qos = qos
+}
+
+func (m *MQTT) SetCredentials(username string, password string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.username = username
+	m.password = password
+}
+
+func (m *MQTT) SetMonitor(monitor func(line datasources.RawLine)) {
+	m.monitor = monitor
+}
+
+func (m *MQTT) Open(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.topics) == 0 {
+		return fmt.Errorf("no mqtt topics entered")
+	}
+	m.messages = make(chan message)
+	m.done = make(chan struct{})
+	m.channels = map[string]bool{}
+	m.disconnected = false
+	m.gap = false
+	messages, done := m.messages, m.done
+	filters := map[string]byte{}
+	for _, topic := range m.topics {
+		filters[topic] = m.qos
+	}
+	options := paho.NewClientOptions()
+	options.AddBroker(m.broker)
+	options.SetClientID(fmt.Sprintf("serial-plotter-%d", time.Now().UnixNano()))
+	options.SetUsername(m.username)
+	options.SetPassword(m.password)
+	options.SetAutoReconnect(true)
+	options.SetConnectTimeout(connectTimeout)
+	options.SetMaxReconnectInterval(5 * time.Second)
+	options.SetConnectionLostHandler(func(client paho.Client, err error) {
+		fmt.Println("lost connection to mqtt broker", err)
+		m.mutex.Lock()
+		m.disconnected = true
+		m.mutex.Unlock()
+	})
+	// The session is clean, so subscribe again on every connect
+	options.SetOnConnectHandler(func(client paho.Client) {
+		m.mutex.Lock()
+		if m.disconnected {
+			m.disconnected = false
+			m.gap = true
+		}
+		m.mutex.Unlock()
+		token := client.SubscribeMultiple(filters, func(client paho.Client, msg paho.Message) {
+			select {
+			case messages <- message{topic: msg.Topic(), payload: msg.Payload(), received: time.Now()}:
+			case <-done:
+			}
+		})
+		go func() {
+			token.Wait()
+			if token.Error() != nil {
+				fmt.Println("failed to subscribe to mqtt topics", token.Error())
+			}
+		}()
+	})
+	client := paho.NewClient(options)
+	token := client.Connect()
+	select {
+	case <-ctx.Done():
+		client.Disconnect(0)
+		return ctx.Err()
+	case <-token.Done():
+	}
+	if token.Error() != nil {
+		return token.Error()
+	}
+	m.client = client
+	return nil
+}
+
+func (m *MQTT) Read(ctx context.Context) ([]datasources.Sample, error) {
+	if gap := m.takeGap(); gap != nil {
+		return gap, nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-m.messages:
+		data, err := decodePayload(msg.topic, msg.payload)
+		if m.monitor != nil {
+			m.monitor(datasources.RawLine{Received: msg.received, Text: msg.topic + " " + string(msg.payload), Err: err})
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.mutex.Lock()
+		for i := range data {
+			data[i].Received = msg.received
+			m.channels[data[i].Name] = true
+		}
+		m.mutex.Unlock()
+		return data, nil
+	}
+}
+
+// takeGap returns a NaN sample for every channel once after a reconnect.
+func (m *MQTT) takeGap() []datasources.Sample {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.gap {
+		return nil
+	}
+	m.gap = false
+	if len(m.channels) == 0 {
+		return nil
+	}
+	received := time.Now()
+	gap := []datasources.Sample{}
+	for channel := range m.channels {
+		gap = append(gap, datasources.Sample{Name: channel, Value: float32(math.NaN()), Received: received})
+	}
+	return gap
+}
+
+func (m *MQTT) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+	if m.client != nil {
+		m.client.Disconnect(disconnectQuiesce)
+		m.client = nil
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// testBroker returns the broker to test against, a local mosquitto unless
+// MQTT_BROKER is set, skipping the test if it isn't running.
+func testBroker(t *testing.T) string {
+	t.Helper()
+	broker := os.Getenv("MQTT_BROKER")
+	if broker == "" {
+		broker = "tcp://localhost:1883"
+	}
+	address, err := url.Parse(broker)
+	if err != nil || address.Host == "" {
+		t.Skipf("invalid mqtt broker %q, want a URL such as tcp://localhost:1883", broker)
+	}
+	conn, err := net.DialTimeout("tcp", address.Host, time.Second)
+	if err != nil {
+		t.Skipf("no mqtt broker at %s", broker)
+	}
+	conn.Close()
+	return broker
+}
+
+func TestSubscribe(t *testing.T) {
+	broker := testBroker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	source := New(broker, []string{"serial-plotter-test/#"}, 1)
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	publisher := paho.NewClient(paho.NewClientOptions().AddBroker(broker))
+	token := publisher.Connect()
+	token.Wait()
+	if token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	defer publisher.Disconnect(0)
+	// Publish until the subscription is in place
+	go func() {
+		for ctx.Err() == nil {
+			publisher.Publish("serial-plotter-test/kitchen", 1, false, `{"temp": 21.5}`).Wait()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	data, err := source.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0].Name != "serial-plotter-test/kitchen/temp" || data[0].Value != 21.5 {
+		t.Errorf("Read = %+v, want serial-plotter-test/kitchen/temp 21.5", data)
+	}
+}
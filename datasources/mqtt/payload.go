@@ -0,0 +1,35 @@
+package mqtt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/jsonmap"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// decodePayload maps a message to samples. A plain number is plotted as a
+// channel named after the topic, and each number inside a JSON object as a
+// channel named after the topic and the field's path, e.g. a payload of
+// {"env":{"temp":21.5}} on sensors/kitchen plots sensors/kitchen/env.temp.
+// Booleans are plotted as 0 or 1.
+func decodePayload(topic string, payload []byte) ([]datasources.Sample, error) {
+	text := strings.TrimSpace(string(payload))
+	if value, err := strconv.ParseFloat(text, 32); err == nil {
+		return []datasources.Sample{{Name: topic, Value: float32(value)}}, nil
+	}
+	document, err := jsonmap.Decode(payload)
+	if err != nil {
+		return nil, serial.NewParseError(fmt.Sprintf("payload on %s is not a number or JSON", topic))
+	}
+	samples := jsonmap.Flatten(document)
+	if len(samples) == 0 {
+		return nil, serial.NewParseError(fmt.Sprintf("payload on %s has no numbers", topic))
+	}
+	for i := range samples {
+		samples[i].Name = topic + "/" + samples[i].Name
+	}
+	return samples, nil
+}
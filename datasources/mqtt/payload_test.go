@@ -0,0 +1,56 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func TestDecodePayload(t *testing.T) {
+	tests := []struct {
+		topic   string
+		payload string
+		want    []datasources.Sample
+		wantErr bool
+	}{
+		{topic: "sensors/kitchen/temp", payload: "21.5", want: []datasources.Sample{{Name: "sensors/kitchen/temp", Value: 21.5}}},
+		{topic: "sensors/kitchen/temp", payload: " -3\n", want: []datasources.Sample{{Name: "sensors/kitchen/temp", Value: -3}}},
+		{
+			topic:   "sensors/kitchen",
+			payload: `{"temp": 21.5, "humidity": 40, "name": "kitchen"}`,
+			want: []datasources.Sample{
+				{Name: "sensors/kitchen/humidity", Value: 40},
+				{Name: "sensors/kitchen/temp", Value: 21.5},
+			},
+		},
+		{
+			topic:   "rig",
+			payload: `{"env": {"temp": 1}, "on": true, "axes": [2, 3]}`,
+			want: []datasources.Sample{
+				{Name: "rig/axes.0", Value: 2},
+				{Name: "rig/axes.1", Value: 3},
+				{Name: "rig/env.temp", Value: 1},
+				{Name: "rig/on", Value: 1},
+			},
+		},
+		{topic: "status", payload: "online", wantErr: true},
+		{topic: "status", payload: `{"state": "online"}`, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := decodePayload(test.topic, []byte(test.payload))
+		if (err != nil) != test.wantErr {
+			t.Errorf("decodePayload(%q, %q) error = %v, wantErr %v", test.topic, test.payload, err, test.wantErr)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("decodePayload(%q, %q) = %+v, want %+v", test.topic, test.payload, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Name != test.want[i].Name || got[i].Value != test.want[i].Value {
+				t.Errorf("decodePayload(%q, %q) = %+v, want %+v", test.topic, test.payload, got, test.want)
+				break
+			}
+		}
+	}
+}
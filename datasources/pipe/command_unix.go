@@ -0,0 +1,35 @@
+//go:build !windows
+
+package pipe
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs command through the shell in its own process group, so
+// killing it also kills anything the shell started.
+func shellCommand(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func killCommand(cmd *exec.Cmd) error {
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// unblockNamedPipe briefly opens a named pipe for writing, which releases a
+// reader waiting in open.
+func unblockNamedPipe(path string) {
+	file, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return
+	}
+	file.Close()
+}
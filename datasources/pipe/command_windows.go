@@ -0,0 +1,14 @@
+package pipe
+
+import "os/exec"
+
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+func killCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// unblockNamedPipe does nothing as named pipes are not files on Windows.
+func unblockNamedPipe(path string) {}
@@ -0,0 +1,307 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// Kind is where a pipe reads its lines from.
+type Kind int
+
+const (
+	Stdin Kind = iota
+	NamedPipe
+	Command
+)
+
+var kindName = map[Kind]string{
+	Stdin:     "Stdin",
+	NamedPipe: "Named Pipe",
+	Command:   "Command",
+}
+
+func (k Kind) String() string {
+	return kindName[k]
+}
+
+func KindOptions() []string {
+	return []string{
+		Stdin.String(),
+		NamedPipe.String(),
+		Command.String(),
+	}
+}
+
+func ParseKind(value string) (Kind, error) {
+	for kind, name := range kindName {
+		if name == value {
+			return kind, nil
+		}
+	}
+	return Stdin, fmt.Errorf("unknown pipe kind (%s)", value)
+}
+
+// chunk is a read from the pipe, or the error that ended it.
+type chunk struct {
+	data []byte
+	err  error
+}
+
+// Pipe reads plotter lines from the plotter's stdin, a named pipe (FIFO) or
+// the stdout of a command run through the shell, such as python sim.py or
+// journalctl -f, so any program can be plotted. Lines are decoded with the
+// same decoder as the serial port.
+//
+// Reads happen in a goroutine since pipes can't be interrupted. A named
+// pipe is reopened when its writer closes it, while the end of stdin or of
+// the command ends plotting.
+type Pipe struct {
+	mutex   sync.Mutex
+	kind    Kind
+	target  string
+	decoder *serial.Decoder
+	monitor func(line datasources.RawLine)
+	cmd     *exec.Cmd
+	file    *os.File
+	chunks  chan chunk
+	done    chan struct{}
+	err     error
+}
+
+// New creates a pipe reading from target, the path of a named pipe or the
+// command line of a command. The target of stdin is ignored.
+func New(kind Kind, target string) *Pipe {
+	return &Pipe{
+		kind:    kind,
+		target:  target,
+		decoder: serial.DefaultFormat().NewDecoder(),
+	}
+}
+
+func (p *Pipe) SetKind(kind Kind) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.kind = kind
+}
+
+func (p *Pipe) SetTarget(target string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.target = target
+}
+
+// SetDecoder sets how the received bytes are framed and parsed.
+func (p *Pipe) SetDecoder(decoder *serial.Decoder) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.decoder = decoder
+}
+
+func (p *Pipe) SetMonitor(monitor func(line datasources.RawLine)) {
+	p.monitor = monitor
+}
+
+func (p *Pipe) Open(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.decoder.Reset()
+	p.err = nil
+	p.chunks = make(chan chunk)
+	p.done = make(chan struct{})
+	switch p.kind {
+	case Stdin:
+		go p.readFile(os.Stdin, p.chunks, p.done)
+	case NamedPipe:
+		if p.target == "" {
+			return fmt.Errorf("no named pipe chosen")
+		}
+		// Opening a named pipe blocks until a writer opens it
+		go p.readNamedPipe(p.target, p.chunks, p.done)
+	case Command:
+		if p.target == "" {
+			return fmt.Errorf("no command entered")
+		}
+		cmd := shellCommand(p.target)
+		cmd.Stderr = os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		err = cmd.Start()
+		if err != nil {
+			return err
+		}
+		p.cmd = cmd
+		go p.readCommand(cmd, stdout, p.chunks, p.done)
+	}
+	return nil
+}
+
+func (p *Pipe) Read(ctx context.Context) ([]datasources.Sample, error) {
+	for {
+		data, ok, err := p.decoder.Next()
+		if !ok {
+			if p.err != nil {
+				return nil, p.err
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case chunk := <-p.chunks:
+				p.decoder.Write(chunk.data)
+				if chunk.err != nil {
+					// Parse a last line left without a terminator
+					p.decoder.End()
+					p.err = chunk.err
+				}
+			}
+			continue
+		}
+		received := time.Now()
+		if p.monitor != nil {
+			p.monitor(datasources.RawLine{Received: received, Text: p.decoder.Line(), Err: err})
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range data {
+			data[i].Received = received
+		}
+		return data, nil
+	}
+}
+
+// readFile sends chunks read from file until it ends or done is closed.
+func (p *Pipe) readFile(file io.Reader, chunks chan<- chunk, done <-chan struct{}) error {
+	for {
+		buff := make([]byte, 4096)
+		n, err := file.Read(buff)
+		next := chunk{data: buff[:n], err: err}
+		if errors.Is(err, errWriterClosed) {
+			next.err = nil
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Println("failed to read pipe", err)
+		}
+		if n > 0 || next.err != nil {
+			select {
+			case chunks <- next:
+			case <-done:
+				return io.EOF
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readNamedPipe reads a named pipe, reopening it each time its writer closes
+// it.
+func (p *Pipe) readNamedPipe(path string, chunks chan<- chunk, done <-chan struct{}) {
+	for {
+		file, err := os.Open(path)
+		if err != nil {
+			select {
+			case chunks <- chunk{err: err}:
+			case <-done:
+			}
+			return
+		}
+		select {
+		case <-done:
+			file.Close()
+			return
+		default:
+		}
+		p.mutex.Lock()
+		p.file = file
+		p.mutex.Unlock()
+		err = p.readFile(eofReader{file}, chunks, done)
+		p.mutex.Lock()
+		p.file = nil
+		p.mutex.Unlock()
+		file.Close()
+		if !errors.Is(err, errWriterClosed) {
+			return
+		}
+	}
+}
+
+// errWriterClosed ends a read of a named pipe when its writer closes, which
+// is not the end of the data as another writer can open it.
+var errWriterClosed = errors.New("named pipe writer closed")
+
+// eofReader reports the end of a named pipe as errWriterClosed so it is
+// reopened rather than ending plotting.
+type eofReader struct {
+	file *os.File
+}
+
+func (r eofReader) Read(data []byte) (int, error) {
+	n, err := r.file.Read(data)
+	if errors.Is(err, io.EOF) {
+		return n, errWriterClosed
+	}
+	return n, err
+}
+
+// readCommand reads the output of a command, ending with its exit error or
+// io.EOF once it exits.
+func (p *Pipe) readCommand(cmd *exec.Cmd, stdout io.Reader, chunks chan<- chunk, done <-chan struct{}) {
+	for {
+		buff := make([]byte, 4096)
+		n, err := stdout.Read(buff)
+		if err != nil {
+			err = cmd.Wait()
+			if err != nil {
+				err = fmt.Errorf("command exited: %w", err)
+			} else {
+				err = io.EOF
+			}
+		}
+		select {
+		case chunks <- chunk{data: buff[:n], err: err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Close stops reading and kills the command. Stdin is left open so plotting
+// can start again, though a read already waiting on it is dropped.
+func (p *Pipe) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.done != nil {
+		close(p.done)
+		p.done = nil
+	}
+	if p.file != nil {
+		// Unblock a read waiting on the named pipe
+		p.file.Close()
+		p.file = nil
+	} else if p.kind == NamedPipe {
+		// Unblock an open waiting for a writer
+		unblockNamedPipe(p.target)
+	}
+	if p.cmd != nil {
+		err := killCommand(p.cmd)
+		if err != nil && !errors.Is(err, os.ErrProcessDone) {
+			fmt.Println("failed to kill command", err)
+		}
+		p.cmd = nil
+	}
+	return nil
+}
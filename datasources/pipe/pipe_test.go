@@ -0,0 +1,118 @@
+//go:build linux
+
+package pipe
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func readAll(t *testing.T, ctx context.Context, source *Pipe, count int) []datasources.Sample {
+	t.Helper()
+	samples := []datasources.Sample{}
+	for len(samples) < count {
+		data, err := source.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read error = %v after %+v", err, samples)
+		}
+		samples = append(samples, data...)
+	}
+	return samples
+}
+
+func TestCommand(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(Command, `printf 'a:1\nb:2'`)
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	samples := readAll(t, ctx, source, 2)
+	if samples[0].Name != "a" || samples[0].Value != 1 || samples[1].Name != "b" || samples[1].Value != 2 {
+		t.Errorf("Read = %+v, want a 1 and b 2", samples)
+	}
+	_, err = source.Read(ctx)
+	if err != io.EOF {
+		t.Errorf("Read error = %v at exit, want %v", err, io.EOF)
+	}
+}
+
+func TestCommandKilledOnClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(Command, `while true; do echo a:1; sleep 0.01; done`)
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, ctx, source, 1)
+	cmd := source.cmd
+	source.Close()
+	done := make(chan struct{})
+	go func() {
+		cmd.Process.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("command still running after Close")
+	}
+}
+
+func TestNamedPipeReopens(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "fifo")
+	err := syscall.Mkfifo(path, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := New(NamedPipe, path)
+	err = source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	for i, line := range []string{"a:1\n", "a:2\n"} {
+		writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.WriteString(line)
+		writer.Close()
+		samples := readAll(t, ctx, source, 1)
+		if samples[0].Name != "a" || samples[0].Value != float32(i+1) {
+			t.Errorf("Read = %+v, want a %d", samples, i+1)
+		}
+	}
+}
+
+func TestNamedPipeCloseWithoutWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fifo")
+	err := syscall.Mkfifo(path, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	source := New(NamedPipe, path)
+	err = source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = source.Read(ctx)
+	if err != context.Canceled {
+		t.Errorf("Read error = %v, want %v", err, context.Canceled)
+	}
+	source.Close()
+}
@@ -0,0 +1,64 @@
+package replay
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/recorder"
+)
+
+// loadCSV reads the raw rows of a recording, grouping rows that were
+// received together into one record. Transformed rows are skipped so the
+// current transform is applied on replay.
+func loadCSV(reader io.Reader) ([]record, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = len(recorder.CSVHeader)
+	_, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording header %v", err)
+	}
+	records := []record{}
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording %v", err)
+		}
+		if row[4] != recorder.Raw {
+			continue
+		}
+		elapsed, err := strconv.ParseFloat(row[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid elapsed time (%s) in recording", row[1])
+		}
+		value, err := strconv.ParseFloat(row[5], 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value (%s) in recording", row[5])
+		}
+		sample := datasources.Sample{
+			Name:  row[3],
+			Value: float32(value),
+		}
+		if row[2] != "" {
+			deviceTime, err := strconv.ParseFloat(row[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid device time (%s) in recording", row[2])
+			}
+			sample.DeviceTime = time.Duration(deviceTime * float64(time.Second))
+			sample.HasDeviceTime = true
+		}
+		offset := time.Duration(elapsed * float64(time.Second))
+		if len(records) > 0 && records[len(records)-1].offset == offset {
+			last := &records[len(records)-1]
+			last.samples = append(last.samples, sample)
+			continue
+		}
+		records = append(records, record{offset: offset, samples: []datasources.Sample{sample}})
+	}
+}
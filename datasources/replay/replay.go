@@ -0,0 +1,379 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/capture"
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+	"github.com/taylorcoons/serial-plotter/recorder"
+)
+
+// DefaultLineInterval is the spacing given to lines of a plain text capture,
+// which carries no timing of its own.
+const DefaultLineInterval = 10 * time.Millisecond
+
+// record is the samples read together at an offset from the start of the file.
+type record struct {
+	offset  time.Duration
+	samples []datasources.Sample
+}
+
+// Replay plays back a CSV recording, a raw capture or a plain text capture of
+// plotter lines. Captures are decoded with the same decoder as the serial
+// port. A recording split into several files is played as one, from the
+// first file chosen through the files rotated after it.
+// A speed of 1 plays at the original rate, 2 at twice the rate and 0 as fast
+// as possible. Samples keep their original spacing on the time axis
+// whatever the playback speed.
+type Replay struct {
+	mutex        sync.Mutex
+	path         string
+	open         func(path string) (io.ReadCloser, error)
+	speed        float64
+	lineInterval time.Duration
+	decoder      *serial.Decoder
+	records      []record
+	position     int
+	paused       bool
+	// clockStart and clockOffset anchor the playback clock, the record at
+	// clockOffset is due at clockStart
+	clockStart  time.Time
+	clockOffset time.Duration
+	base        time.Time
+	changed     chan struct{}
+}
+
+func SpeedOptions() []string {
+	return []string{
+		"0.25x",
+		"0.5x",
+		"1x",
+		"2x",
+		"5x",
+		"10x",
+		"Max",
+	}
+}
+
+// ParseSpeed converts a speed option such as 2x or Max into a speed.
+func ParseSpeed(value string) (float64, error) {
+	if value == "Max" {
+		return 0, nil
+	}
+	if len(value) > 0 && value[len(value)-1] == 'x' {
+		value = value[:len(value)-1]
+	}
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil || speed < 0 {
+		return 0, fmt.Errorf("invalid replay speed (%s)", value)
+	}
+	return speed, nil
+}
+
+func New(path string, speed float64) *Replay {
+	return &Replay{
+		path: path,
+		open: func(path string) (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		speed:        speed,
+		lineInterval: DefaultLineInterval,
+		decoder:      serial.NewDecoder(serial.NewFramer([]byte("\n"), serial.DefaultMaxLineLength), serial.NewArduinoParser(serial.GapNonFinite)),
+		changed:      make(chan struct{}),
+	}
+}
+
+func (r *Replay) SetPath(path string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.path = path
+}
+
+// SetOpen sets how files are opened, such as through a storage API rather
+// than the file system.
+func (r *Replay) SetOpen(open func(path string) (io.ReadCloser, error)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.open = open
+}
+
+func (r *Replay) SetSpeed(speed float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.restartClock()
+	r.speed = speed
+	r.notify()
+}
+
+// SetDecoder sets how raw and plain text captures are framed and parsed.
+func (r *Replay) SetDecoder(decoder *serial.Decoder) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.decoder = decoder
+}
+
+func (r *Replay) SetLineInterval(lineInterval time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lineInterval = lineInterval
+}
+
+func (r *Replay) Open(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.decoder.Reset()
+	records, err := load(r.path, r.open, r.lineInterval, r.decoder)
+	if err != nil {
+		return err
+	}
+	r.records = records
+	r.position = 0
+	r.paused = false
+	r.base = time.Now()
+	r.clockStart = r.base
+	r.clockOffset = 0
+	return nil
+}
+
+func (r *Replay) Read(ctx context.Context) ([]datasources.Sample, error) {
+	for {
+		r.mutex.Lock()
+		if r.position >= len(r.records) {
+			r.mutex.Unlock()
+			return nil, io.EOF
+		}
+		changed := r.changed
+		var timer *time.Timer
+		if !r.paused {
+			wait := time.Duration(0)
+			if r.speed > 0 {
+				due := r.clockStart.Add(time.Duration(float64(r.records[r.position].offset-r.clockOffset) / r.speed))
+				wait = time.Until(due)
+			}
+			if wait <= 0 {
+				samples := r.samples(r.records[r.position])
+				r.position++
+				r.mutex.Unlock()
+				return samples, nil
+			}
+			timer = time.NewTimer(wait)
+		}
+		r.mutex.Unlock()
+
+		// A paused replay has no timer and waits for a change
+		var due <-chan time.Time
+		if timer != nil {
+			due = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, err
+		case <-changed:
+		case <-due:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// samples stamps a record as if it were received at its original offset.
+func (r *Replay) samples(rec record) []datasources.Sample {
+	samples := make([]datasources.Sample, len(rec.samples))
+	for i, sample := range rec.samples {
+		sample.Received = r.base.Add(rec.offset)
+		samples[i] = sample
+	}
+	return samples
+}
+
+func (r *Replay) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records = nil
+	r.position = 0
+	r.notify()
+	return nil
+}
+
+// notify wakes a Read waiting on the playback clock.
+func (r *Replay) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// restartClock anchors the playback clock at the current position.
+func (r *Replay) restartClock() {
+	r.clockStart = time.Now()
+	if r.position < len(r.records) {
+		r.clockOffset = r.records[r.position].offset
+	}
+}
+
+func (r *Replay) Pause() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.paused = true
+	r.notify()
+}
+
+func (r *Replay) Resume() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.paused = false
+	r.restartClock()
+	r.notify()
+}
+
+func (r *Replay) Paused() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.paused
+}
+
+// Seek moves playback to the first record at or after offset.
+func (r *Replay) Seek(offset time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.position = sort.Search(len(r.records), func(i int) bool {
+		return r.records[i].offset >= offset
+	})
+	r.restartClock()
+	r.notify()
+}
+
+// Position returns the offset of the next record to be played.
+func (r *Replay) Position() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.position < len(r.records) {
+		return r.records[r.position].offset
+	}
+	return r.duration()
+}
+
+func (r *Replay) Duration() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.duration()
+}
+
+func (r *Replay) duration() time.Duration {
+	if len(r.records) == 0 {
+		return 0
+	}
+	return r.records[len(r.records)-1].offset
+}
+
+func load(path string, open func(path string) (io.ReadCloser, error), lineInterval time.Duration, decoder *serial.Decoder) ([]record, error) {
+	file, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file %v", err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	if capture.IsCapture(reader) {
+		return loadCapture(reader, decoder)
+	}
+	if isCSV(reader) {
+		records, err := loadCSV(reader)
+		if err != nil {
+			return nil, err
+		}
+		return loadRotated(records, path, open)
+	}
+	return loadText(reader, lineInterval, decoder)
+}
+
+func isCSV(reader *bufio.Reader) bool {
+	csvHeader := strings.Join(recorder.CSVHeader, ",")
+	header, err := reader.Peek(len(csvHeader))
+	return err == nil && string(header) == csvHeader
+}
+
+// loadRotated appends the records of the files a recording rotated into
+// after path, stopping at the first that doesn't open. Offsets count from
+// the start of the recording in every file so they carry straight on.
+func loadRotated(records []record, path string, open func(path string) (io.ReadCloser, error)) ([]record, error) {
+	for index := 1; ; index++ {
+		file, err := open(recorder.RotatedPath(path, index))
+		if err != nil {
+			return records, nil
+		}
+		reader := bufio.NewReader(file)
+		if !isCSV(reader) {
+			file.Close()
+			return records, nil
+		}
+		rotated, err := loadCSV(reader)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rotated...)
+	}
+}
+
+// decode appends a record for each line the decoder has buffered.
+func decode(records []record, decoder *serial.Decoder, offset func() time.Duration) []record {
+	for {
+		samples, ok, err := decoder.Next()
+		if !ok {
+			return records
+		}
+		if err != nil {
+			fmt.Println("failed to parse replay line, skipping", err)
+			continue
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		records = append(records, record{offset: offset(), samples: samples})
+	}
+}
+
+// loadCapture decodes a raw capture, lines take the time of the chunk that
+// completed them.
+func loadCapture(reader io.Reader, decoder *serial.Decoder) ([]record, error) {
+	chunks, err := capture.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	records := []record{}
+	for _, chunk := range chunks {
+		decoder.Write(chunk.Data)
+		records = decode(records, decoder, func() time.Duration {
+			return chunk.Offset
+		})
+	}
+	return records, nil
+}
+
+// loadText decodes a capture of plotter lines without timing, lines are
+// spaced by the line interval.
+func loadText(reader io.Reader, lineInterval time.Duration, decoder *serial.Decoder) ([]record, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay file %v", err)
+	}
+	decoder.Write(data)
+	index := 0
+	return decode([]record{}, decoder, func() time.Duration {
+		index++
+		return time.Duration(index-1) * lineInterval
+	}), nil
+}
@@ -0,0 +1,215 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/recorder"
+)
+
+// writeRecording records the samples, each group received step after the
+// last, and returns the recording's path.
+func writeRecording(t *testing.T, groups [][]datasources.Sample, step time.Duration) string {
+	t.Helper()
+	return writeRotatedRecording(t, groups, step, recorder.DefaultMaxRows)
+}
+
+// writeRotatedRecording records the samples starting a new file every
+// maxRows rows.
+func writeRotatedRecording(t *testing.T, groups [][]datasources.Sample, step time.Duration, maxRows int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "run.csv")
+	recording := recorder.NewCSV(path, maxRows)
+	err := recording.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := time.Now()
+	for _, group := range groups {
+		for _, sample := range group {
+			sample.Received = received
+			err := recording.Write(recorder.Raw, sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Transformed rows are skipped on replay
+			err = recording.Write(recorder.Transformed, sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		received = received.Add(step)
+	}
+	err = recording.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, source *Replay) [][]datasources.Sample {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	groups := [][]datasources.Sample{}
+	for {
+		samples, err := source.Read(ctx)
+		if err == io.EOF {
+			return groups
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range samples {
+			samples[i].Received = time.Time{}
+		}
+		groups = append(groups, samples)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	groups := [][]datasources.Sample{
+		{{Name: "temp", Value: 21.5}, {Name: "rh", Value: 40}},
+		{{Name: "temp", Value: 21.75, DeviceTime: 1500 * time.Millisecond, HasDeviceTime: true}},
+		{{Name: "rh", Value: -3.25}},
+	}
+	source := New(writeRecording(t, groups, 10*time.Millisecond), 0)
+	err := source.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	// Offsets count from when the recording was opened
+	first := source.Position()
+	if source.Duration()-first != 20*time.Millisecond {
+		t.Errorf("Duration = %v, want 20ms after the first record at %v", source.Duration(), first)
+	}
+	got := readAll(t, source)
+	if !reflect.DeepEqual(got, groups) {
+		t.Errorf("replayed %v, want %v", got, groups)
+	}
+}
+
+func TestSpeedScaling(t *testing.T) {
+	groups := [][]datasources.Sample{}
+	for i := range 5 {
+		groups = append(groups, []datasources.Sample{{Name: "x", Value: float32(i)}})
+	}
+	path := writeRecording(t, groups, 50*time.Millisecond)
+	tests := []struct {
+		speed float64
+		want  time.Duration
+	}{
+		{speed: 1, want: 200 * time.Millisecond},
+		{speed: 2, want: 100 * time.Millisecond},
+		{speed: 0, want: 0},
+	}
+	for _, test := range tests {
+		source := New(path, test.speed)
+		err := source.Open(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		got := readAll(t, source)
+		elapsed := time.Since(start)
+		source.Close()
+		if len(got) != len(groups) {
+			t.Errorf("speed %v replayed %d records, want %d", test.speed, len(got), len(groups))
+		}
+		if elapsed < test.want || elapsed > test.want+80*time.Millisecond {
+			t.Errorf("speed %v took %v, want %v", test.speed, elapsed, test.want)
+		}
+	}
+}
+
+func TestPauseAndSeek(t *testing.T) {
+	groups := [][]datasources.Sample{}
+	for i := range 10 {
+		groups = append(groups, []datasources.Sample{{Name: "x", Value: float32(i)}})
+	}
+	source := New(writeRecording(t, groups, time.Second), 1)
+	err := source.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	first := source.Position()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	samples, err := source.Read(ctx)
+	if err != nil || samples[0].Value != 0 {
+		t.Fatalf("Read = %v, %v, want the first record", samples, err)
+	}
+
+	source.Pause()
+	source.Seek(first + 7*time.Second)
+	if source.Position() != first+7*time.Second {
+		t.Errorf("Position = %v, want 7s after %v", source.Position(), first)
+	}
+	pausedCtx, cancelPaused := context.WithTimeout(ctx, 50*time.Millisecond)
+	_, err = source.Read(pausedCtx)
+	cancelPaused()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Read while paused = %v, want it to wait", err)
+	}
+
+	// After resuming the record sought to is due straight away
+	source.Resume()
+	samples, err = source.Read(ctx)
+	if err != nil || samples[0].Value != 7 {
+		t.Fatalf("Read after seek = %v, %v, want the record at 7s", samples, err)
+	}
+}
+
+func TestLoadText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	err := os.WriteFile(path, []byte("a:1 b:2\nnot a number here\na:3\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := New(path, 0)
+	source.SetLineInterval(5 * time.Millisecond)
+	err = source.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	got := readAll(t, source)
+	want := [][]datasources.Sample{
+		{{Name: "a", Value: 1}, {Name: "b", Value: 2}},
+		{{Name: "a", Value: 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestRotatedRecording(t *testing.T) {
+	groups := [][]datasources.Sample{}
+	for i := range 5 {
+		groups = append(groups, []datasources.Sample{{Name: "x", Value: float32(i)}})
+	}
+	// Raw and transformed rows for each sample, so three rotated files
+	path := writeRotatedRecording(t, groups, time.Millisecond, 4)
+	_, err := os.Stat(recorder.RotatedPath(path, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := New(path, 0)
+	err = source.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	got := readAll(t, source)
+	if !reflect.DeepEqual(got, groups) {
+		t.Errorf("replayed %v, want %v", got, groups)
+	}
+}
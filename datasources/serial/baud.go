@@ -0,0 +1,118 @@
+package serial
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// AutoBaud is the baud option that detects the rate when the port opens.
+	AutoBaud = "Auto"
+
+	minBaud = 50
+	maxBaud = 12000000
+
+	// detectWindow is how long each candidate rate is listened to.
+	detectWindow = 1200 * time.Millisecond
+	// detectLines is enough clean lines to accept a rate without trying
+	// the rest.
+	detectLines = 5
+)
+
+// ParseBaud converts a free-form baud rate such as 14400 or 128000.
+func ParseBaud(value string) (int, error) {
+	baud, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("baud must be a whole number (%s)", value)
+	}
+	if baud < minBaud || baud > maxBaud {
+		return 0, fmt.Errorf("baud must be between %d and %d (%d)", minBaud, maxBaud, baud)
+	}
+	return baud, nil
+}
+
+func baudCandidates() []int {
+	candidates := []int{}
+	for _, option := range BaudOptions() {
+		baud, err := ParseBaud(option)
+		if err == nil {
+			candidates = append(candidates, baud)
+		}
+	}
+	return candidates
+}
+
+// detectBaud listens to the open port at each candidate rate and locks onto
+// the rate yielding the most cleanly parsed lines. The port is switched
+// between rates rather than reopened so the board is not reset each time.
+func (s *SerialPort) detectBaud(ctx context.Context, candidates []int) (int, error) {
+	bestBaud := 0
+	bestScore := 0
+	for _, baud := range candidates {
+		score, err := s.scoreBaud(ctx, baud)
+		if err != nil {
+			return 0, err
+		}
+		if score > bestScore {
+			bestBaud = baud
+			bestScore = score
+		}
+		if score >= detectLines {
+			break
+		}
+	}
+	if bestBaud == 0 {
+		return 0, fmt.Errorf("no baud rate produced parseable data")
+	}
+	return bestBaud, s.port.SetMode(s.config.mode(bestBaud))
+}
+
+// scoreBaud counts the clean lines received at a rate within the detect
+// window, less any lines that failed to frame or parse.
+func (s *SerialPort) scoreBaud(ctx context.Context, baud int) (int, error) {
+	err := s.port.SetMode(s.config.mode(baud))
+	if err != nil {
+		return 0, err
+	}
+	err = s.port.ResetInputBuffer()
+	if err != nil {
+		return 0, err
+	}
+	decoder := s.decoder.Clone()
+	// The first line is usually cut short by switching rates mid line
+	skipped := false
+	score := 0
+	deadline := time.Now().Add(detectWindow)
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n, err := s.port.Read(s.buff)
+		if err != nil {
+			return 0, err
+		}
+		decoder.Write(s.buff[:n])
+		for {
+			samples, ok, err := decoder.Next()
+			if !ok {
+				break
+			}
+			if !skipped {
+				skipped = true
+				continue
+			}
+			if err != nil || len(samples) == 0 {
+				score--
+				continue
+			}
+			score++
+		}
+		if score >= detectLines {
+			break
+		}
+	}
+	return score - decoder.FramingErrors(), nil
+}
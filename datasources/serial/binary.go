@@ -0,0 +1,267 @@
+package serial
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// maxFrameLength bounds a binary frame, a longer run of bytes without a
+// delimiter is dropped and counted as a framing error.
+const maxFrameLength = 1024
+
+// Framing is how a byte stream is split into frames.
+type Framing int
+
+const (
+	// LineFraming splits text lines on a terminator
+	LineFraming Framing = iota
+	// COBSFraming splits packets encoded with Consistent Overhead Byte
+	// Stuffing, each followed by a zero byte
+	COBSFraming
+	// SLIPFraming splits packets encoded with SLIP (RFC 1055)
+	SLIPFraming
+	// SyncFraming splits packets starting with a sync word, see SyncSplitter
+	SyncFraming
+)
+
+var framingName = map[Framing]string{
+	LineFraming: "Lines",
+	COBSFraming: "COBS",
+	SLIPFraming: "SLIP",
+	SyncFraming: "Sync+Length+CRC16",
+}
+
+func (f Framing) String() string {
+	return framingName[f]
+}
+
+func FramingOptions() []string {
+	return []string{
+		LineFraming.String(),
+		COBSFraming.String(),
+		SLIPFraming.String(),
+		SyncFraming.String(),
+	}
+}
+
+func ParseFraming(value string) (Framing, error) {
+	for framing, name := range framingName {
+		if name == value {
+			return framing, nil
+		}
+	}
+	return LineFraming, fmt.Errorf("unknown framing (%s)", value)
+}
+
+// delimitedSplitter splits packets ending in a delimiter byte and decodes
+// each with decode. Packets that fail to decode are counted as errors.
+type delimitedSplitter struct {
+	delimiter  byte
+	decode     func(packet []byte) ([]byte, error)
+	pending    []byte
+	discarding bool
+	errors     int
+}
+
+func (d *delimitedSplitter) Write(chunk []byte) {
+	d.pending = append(d.pending, chunk...)
+}
+
+func (d *delimitedSplitter) Next() ([]byte, bool) {
+	for {
+		index := bytes.IndexByte(d.pending, d.delimiter)
+		if index < 0 {
+			if len(d.pending) > maxFrameLength*2 {
+				if !d.discarding {
+					d.errors++
+					d.discarding = true
+				}
+				d.pending = nil
+			}
+			return nil, false
+		}
+		packet := d.pending[:index]
+		d.pending = d.pending[index+1:]
+		if d.discarding {
+			d.discarding = false
+			continue
+		}
+		// Senders often lead with a delimiter to flush line noise
+		if len(packet) == 0 {
+			continue
+		}
+		frame, err := d.decode(packet)
+		if err != nil {
+			d.errors++
+			continue
+		}
+		return frame, true
+	}
+}
+
+func (d *delimitedSplitter) End() {
+	if len(d.pending) > 0 {
+		d.pending = append(d.pending, d.delimiter)
+	}
+}
+
+func (d *delimitedSplitter) Errors() int {
+	return d.errors
+}
+
+func (d *delimitedSplitter) Reset() {
+	d.pending = nil
+	d.discarding = false
+	d.errors = 0
+}
+
+func (d *delimitedSplitter) Clone() Splitter {
+	return &delimitedSplitter{delimiter: d.delimiter, decode: d.decode}
+}
+
+// NewCOBSSplitter splits COBS encoded packets, each followed by a zero
+// byte.
+func NewCOBSSplitter() Splitter {
+	return &delimitedSplitter{delimiter: 0, decode: cobsDecode}
+}
+
+func cobsDecode(packet []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(packet))
+	for i := 0; i < len(packet); {
+		code := int(packet[i])
+		if code == 0 {
+			return nil, fmt.Errorf("zero byte inside cobs packet")
+		}
+		i++
+		if i+code-1 > len(packet) {
+			return nil, fmt.Errorf("cobs packet is truncated")
+		}
+		frame = append(frame, packet[i:i+code-1]...)
+		i += code - 1
+		// A full block of 254 bytes is not followed by an encoded zero
+		if code < 0xff && i < len(packet) {
+			frame = append(frame, 0)
+		}
+	}
+	return frame, nil
+}
+
+const (
+	slipEnd       = 0xc0
+	slipEscape    = 0xdb
+	slipEscapeEnd = 0xdc
+	slipEscapeEsc = 0xdd
+)
+
+// NewSLIPSplitter splits SLIP encoded packets.
+func NewSLIPSplitter() Splitter {
+	return &delimitedSplitter{delimiter: slipEnd, decode: slipDecode}
+}
+
+func slipDecode(packet []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(packet))
+	for i := 0; i < len(packet); i++ {
+		if packet[i] != slipEscape {
+			frame = append(frame, packet[i])
+			continue
+		}
+		i++
+		if i == len(packet) {
+			return nil, fmt.Errorf("slip packet ends in an escape")
+		}
+		switch packet[i] {
+		case slipEscapeEnd:
+			frame = append(frame, slipEnd)
+		case slipEscapeEsc:
+			frame = append(frame, slipEscape)
+		default:
+			return nil, fmt.Errorf("invalid slip escape 0x%02x", packet[i])
+		}
+	}
+	return frame, nil
+}
+
+// SyncWord starts each packet split by a SyncSplitter.
+var SyncWord = []byte{0xaa, 0x55}
+
+// SyncSplitter splits packets laid out as the sync word 0xAA 0x55, a length
+// byte, that many payload bytes, then a CRC16 of the length and payload,
+// high byte first. The CRC is CRC-16/CCITT-FALSE (polynomial 0x1021,
+// initial value 0xFFFF). A packet failing its CRC is counted as an error
+// and the search for the next sync word starts just after it.
+type SyncSplitter struct {
+	pending []byte
+	errors  int
+}
+
+func NewSyncSplitter() *SyncSplitter {
+	return &SyncSplitter{}
+}
+
+func (s *SyncSplitter) Write(chunk []byte) {
+	s.pending = append(s.pending, chunk...)
+}
+
+func (s *SyncSplitter) Next() ([]byte, bool) {
+	for {
+		index := bytes.Index(s.pending, SyncWord)
+		if index < 0 {
+			// Keep a byte that may be the start of a sync word split across writes
+			if len(s.pending) >= len(SyncWord) {
+				s.pending = s.pending[len(s.pending)-len(SyncWord)+1:]
+			}
+			return nil, false
+		}
+		s.pending = s.pending[index:]
+		header := len(SyncWord) + 1
+		if len(s.pending) < header {
+			return nil, false
+		}
+		length := int(s.pending[len(SyncWord)])
+		total := header + length + 2
+		if len(s.pending) < total {
+			return nil, false
+		}
+		crc := uint16(s.pending[total-2])<<8 | uint16(s.pending[total-1])
+		if crc != crc16CCITT(s.pending[len(SyncWord):total-2]) {
+			s.errors++
+			s.pending = s.pending[1:]
+			continue
+		}
+		frame := bytes.Clone(s.pending[header : total-2])
+		s.pending = s.pending[total:]
+		return frame, true
+	}
+}
+
+// End does nothing, sync framed packets carry their own length.
+func (s *SyncSplitter) End() {}
+
+func (s *SyncSplitter) Errors() int {
+	return s.errors
+}
+
+func (s *SyncSplitter) Reset() {
+	s.pending = nil
+	s.errors = 0
+}
+
+func (s *SyncSplitter) Clone() Splitter {
+	return NewSyncSplitter()
+}
+
+// crc16CCITT computes CRC-16/CCITT-FALSE.
+func crc16CCITT(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
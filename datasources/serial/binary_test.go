@@ -0,0 +1,122 @@
+package serial
+
+import (
+	"bytes"
+	"testing"
+)
+
+func splitAll(splitter Splitter, chunks ...[]byte) [][]byte {
+	frames := [][]byte{}
+	for _, chunk := range chunks {
+		splitter.Write(chunk)
+		for {
+			frame, ok := splitter.Next()
+			if !ok {
+				break
+			}
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+func TestCOBSSplitter(t *testing.T) {
+	block := make([]byte, 254)
+	for i := range block {
+		block[i] = byte(i + 1)
+	}
+	tests := []struct {
+		encoded []byte
+		want    []byte
+	}{
+		{encoded: []byte{0x01, 0x01, 0x00}, want: []byte{0x00}},
+		{encoded: []byte{0x01, 0x01, 0x01, 0x00}, want: []byte{0x00, 0x00}},
+		{encoded: []byte{0x03, 0x11, 0x22, 0x02, 0x33, 0x00}, want: []byte{0x11, 0x22, 0x00, 0x33}},
+		{encoded: []byte{0x05, 0x11, 0x22, 0x33, 0x44, 0x00}, want: []byte{0x11, 0x22, 0x33, 0x44}},
+		{encoded: []byte{0x02, 0x11, 0x01, 0x01, 0x01, 0x00}, want: []byte{0x11, 0x00, 0x00, 0x00}},
+		{encoded: append(append([]byte{0xff}, block...), 0x00), want: block},
+	}
+	for _, test := range tests {
+		splitter := NewCOBSSplitter()
+		frames := splitAll(splitter, test.encoded[:2], test.encoded[2:])
+		if len(frames) != 1 || !bytes.Equal(frames[0], test.want) {
+			t.Errorf("COBS % x = % x, want % x", test.encoded, frames, test.want)
+		}
+		if splitter.Errors() != 0 {
+			t.Errorf("COBS % x errors = %d, want 0", test.encoded, splitter.Errors())
+		}
+	}
+}
+
+func TestCOBSSplitterErrors(t *testing.T) {
+	splitter := NewCOBSSplitter()
+	// The first packet claims more bytes than it has
+	frames := splitAll(splitter, []byte{0x05, 0x11, 0x00, 0x02, 0x11, 0x00})
+	if len(frames) != 1 || !bytes.Equal(frames[0], []byte{0x11}) {
+		t.Errorf("frames = % x, want [11]", frames)
+	}
+	if splitter.Errors() != 1 {
+		t.Errorf("errors = %d, want 1", splitter.Errors())
+	}
+}
+
+func TestSLIPSplitter(t *testing.T) {
+	splitter := NewSLIPSplitter()
+	frames := splitAll(splitter,
+		[]byte{0xc0, 0xdb, 0xdc, 0xdb},
+		[]byte{0xdd, 0x01, 0xc0, 0x02, 0xdb, 0x03, 0xc0, 0x04, 0xc0},
+	)
+	want := [][]byte{{0xc0, 0xdb, 0x01}, {0x04}}
+	if len(frames) != len(want) {
+		t.Fatalf("frames = % x, want % x", frames, want)
+	}
+	for i := range want {
+		if !bytes.Equal(frames[i], want[i]) {
+			t.Errorf("frame %d = % x, want % x", i, frames[i], want[i])
+		}
+	}
+	if splitter.Errors() != 1 {
+		t.Errorf("errors = %d, want 1", splitter.Errors())
+	}
+}
+
+func TestCRC16CCITT(t *testing.T) {
+	if got := crc16CCITT([]byte("123456789")); got != 0x29b1 {
+		t.Errorf("crc16CCITT(123456789) = %04x, want 29b1", got)
+	}
+}
+
+func syncPacket(payload []byte) []byte {
+	body := append([]byte{byte(len(payload))}, payload...)
+	crc := crc16CCITT(body)
+	packet := append(bytes.Clone(SyncWord), body...)
+	return append(packet, byte(crc>>8), byte(crc))
+}
+
+func TestSyncSplitter(t *testing.T) {
+	good := syncPacket([]byte{0x01, 0xaa, 0x55, 0x02})
+	bad := syncPacket([]byte{0x03, 0x04})
+	bad[len(bad)-1] ^= 0xff
+	stream := append([]byte{0x00, 0xaa}, bad...)
+	stream = append(stream, good...)
+	stream = append(stream, syncPacket(nil)...)
+
+	splitter := NewSyncSplitter()
+	frames := [][]byte{}
+	// Byte at a time to split sync words and CRCs across writes
+	for _, b := range stream {
+		frames = append(frames, splitAll(splitter, []byte{b})...)
+	}
+	want := [][]byte{{0x01, 0xaa, 0x55, 0x02}, {}}
+	if len(frames) != len(want) {
+		t.Fatalf("frames = % x, want % x", frames, want)
+	}
+	for i := range want {
+		if !bytes.Equal(frames[i], want[i]) {
+			t.Errorf("frame %d = % x, want % x", i, frames[i], want[i])
+		}
+	}
+	if splitter.Errors() != 1 {
+		t.Errorf("errors = %d, want 1", splitter.Errors())
+	}
+}
@@ -0,0 +1,193 @@
+package serial
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.bug.st/serial"
+)
+
+// FlowControl is emulated in software, as go.bug.st/serial can't enable the
+// driver's hardware or software flow control. Neither option can stop the
+// device sending, so data arriving faster than it is read can still be lost.
+type FlowControl int
+
+const (
+	NoFlowControl FlowControl = iota
+	// RTSCTSFlowControl holds RTS asserted while the port is open and waits
+	// for CTS before each write, the driver doesn't drop RTS when its buffer
+	// fills.
+	RTSCTSFlowControl
+	// XONXOFFFlowControl strips XON and XOFF from received data and holds
+	// writes after an XOFF, it never sends XOFF itself.
+	XONXOFFFlowControl
+)
+
+const (
+	xon  = 0x11
+	xoff = 0x13
+)
+
+var flowControlName = map[FlowControl]string{
+	NoFlowControl:      "None",
+	RTSCTSFlowControl:  "RTS/CTS (emulated)",
+	XONXOFFFlowControl: "XON/XOFF (emulated)",
+}
+
+// legacyFlowControlName are names saved before flow control was labelled
+// as emulated.
+var legacyFlowControlName = map[string]FlowControl{
+	"RTS/CTS":  RTSCTSFlowControl,
+	"XON/XOFF": XONXOFFFlowControl,
+}
+
+func (f FlowControl) String() string {
+	return flowControlName[f]
+}
+
+var parityName = map[serial.Parity]string{
+	serial.NoParity:    "None",
+	serial.OddParity:   "Odd",
+	serial.EvenParity:  "Even",
+	serial.MarkParity:  "Mark",
+	serial.SpaceParity: "Space",
+}
+
+var parityLetter = map[serial.Parity]string{
+	serial.NoParity:    "N",
+	serial.OddParity:   "O",
+	serial.EvenParity:  "E",
+	serial.MarkParity:  "M",
+	serial.SpaceParity: "S",
+}
+
+var stopBitsName = map[serial.StopBits]string{
+	serial.OneStopBit:           "1",
+	serial.OnePointFiveStopBits: "1.5",
+	serial.TwoStopBits:          "2",
+}
+
+// Config is the line settings of a serial port besides its baud rate.
+type Config struct {
+	DataBits    int
+	Parity      serial.Parity
+	StopBits    serial.StopBits
+	FlowControl FlowControl
+}
+
+func DefaultConfig() Config {
+	return Config{
+		DataBits:    8,
+		Parity:      serial.NoParity,
+		StopBits:    serial.OneStopBit,
+		FlowControl: NoFlowControl,
+	}
+}
+
+// String returns the conventional short form of the settings, such as 8N1.
+func (c Config) String() string {
+	return strconv.Itoa(c.DataBits) + parityLetter[c.Parity] + stopBitsName[c.StopBits]
+}
+
+func (c Config) Validate() error {
+	if c.DataBits < 5 || c.DataBits > 8 {
+		return fmt.Errorf("data bits must be between 5 and 8, not %d", c.DataBits)
+	}
+	if _, ok := parityName[c.Parity]; !ok {
+		return fmt.Errorf("unknown parity %d", c.Parity)
+	}
+	if _, ok := stopBitsName[c.StopBits]; !ok {
+		return fmt.Errorf("unknown stop bits %d", c.StopBits)
+	}
+	if c.StopBits == serial.OnePointFiveStopBits && c.DataBits != 5 {
+		return fmt.Errorf("1.5 stop bits can only be used with 5 data bits, not %d", c.DataBits)
+	}
+	if _, ok := flowControlName[c.FlowControl]; !ok {
+		return fmt.Errorf("unknown flow control %d", c.FlowControl)
+	}
+	return nil
+}
+
+func (c Config) mode(baud int) *serial.Mode {
+	return &serial.Mode{
+		BaudRate: baud,
+		DataBits: c.DataBits,
+		Parity:   c.Parity,
+		StopBits: c.StopBits,
+	}
+}
+
+func DataBitsOptions() []string {
+	return []string{"5", "6", "7", "8"}
+}
+
+func ParityOptions() []string {
+	return []string{"None", "Odd", "Even", "Mark", "Space"}
+}
+
+func StopBitsOptions() []string {
+	return []string{"1", "1.5", "2"}
+}
+
+func FlowControlOptions() []string {
+	return []string{
+		NoFlowControl.String(),
+		RTSCTSFlowControl.String(),
+		XONXOFFFlowControl.String(),
+	}
+}
+
+func ParseDataBits(value string) (int, error) {
+	dataBits, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid data bits (%s)", value)
+	}
+	return dataBits, nil
+}
+
+func ParseParity(value string) (serial.Parity, error) {
+	for parity, name := range parityName {
+		if name == value {
+			return parity, nil
+		}
+	}
+	return serial.NoParity, fmt.Errorf("unknown parity (%s)", value)
+}
+
+func ParseStopBits(value string) (serial.StopBits, error) {
+	for stopBits, name := range stopBitsName {
+		if name == value {
+			return stopBits, nil
+		}
+	}
+	return serial.OneStopBit, fmt.Errorf("unknown stop bits (%s)", value)
+}
+
+func ParseFlowControl(value string) (FlowControl, error) {
+	for flowControl, name := range flowControlName {
+		if name == value {
+			return flowControl, nil
+		}
+	}
+	if flowControl, ok := legacyFlowControlName[value]; ok {
+		return flowControl, nil
+	}
+	return NoFlowControl, fmt.Errorf("unknown flow control (%s)", value)
+}
+
+// stripFlowControl removes XON and XOFF bytes from data in place, returning
+// the remaining length and the last flow control byte seen, or 0 if there
+// was none.
+func stripFlowControl(data []byte) (int, byte) {
+	n := 0
+	var last byte
+	for _, b := range data {
+		if b == xon || b == xoff {
+			last = b
+			continue
+		}
+		data[n] = b
+		n++
+	}
+	return n, last
+}
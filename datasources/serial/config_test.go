@@ -0,0 +1,28 @@
+package serial
+
+import "testing"
+
+func TestParseFlowControl(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    FlowControl
+		wantErr bool
+	}{
+		{value: "None", want: NoFlowControl},
+		{value: "RTS/CTS (emulated)", want: RTSCTSFlowControl},
+		{value: "XON/XOFF (emulated)", want: XONXOFFFlowControl},
+		{value: "RTS/CTS", want: RTSCTSFlowControl},
+		{value: "XON/XOFF", want: XONXOFFFlowControl},
+		{value: "DTR/DSR", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseFlowControl(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseFlowControl(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && got != test.want {
+			t.Errorf("ParseFlowControl(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package serial
+
+import "github.com/taylorcoons/serial-plotter/datasources"
+
+// Splitter splits a stream of raw bytes into frames, such as lines of text
+// or binary packets. Bytes that can't be framed are counted as errors.
+type Splitter interface {
+	// Write buffers a chunk of raw bytes.
+	Write(chunk []byte)
+	// Next returns the next complete frame, it returns false when no
+	// complete frame is buffered.
+	Next() ([]byte, bool)
+	// End marks the end of a message, such as a datagram, so a final frame
+	// without a delimiter is still framed.
+	End()
+	Errors() int
+	Reset()
+	// Clone returns a splitter with the same settings and nothing buffered.
+	Clone() Splitter
+}
+
+// FrameParser parses a frame into samples.
+type FrameParser interface {
+	ParseFrame(frame []byte) ([]datasources.Sample, error)
+	// Text returns a frame as it is shown in the raw monitor.
+	Text(frame []byte) string
+	Reset()
+	// Clone returns a parser with the same settings and no state.
+	Clone() FrameParser
+}
+
+// Decoder splits raw bytes into frames and parses each frame into samples.
+// Bytes read from a port and bytes replayed from a capture take the same
+// path through a decoder.
+type Decoder struct {
+	splitter Splitter
+	parser   FrameParser
+	frame    []byte
+}
+
+func NewDecoder(splitter Splitter, parser FrameParser) *Decoder {
+	return &Decoder{
+		splitter: splitter,
+		parser:   parser,
+	}
+}
+
+// Write buffers a chunk of raw bytes.
+func (d *Decoder) Write(chunk []byte) {
+	d.splitter.Write(chunk)
+}
+
+// End marks the end of a message so its last frame is parsed even without
+// a terminator.
+func (d *Decoder) End() {
+	d.splitter.End()
+}
+
+// Next parses the next complete frame, it returns false when no complete
+// frame is buffered.
+func (d *Decoder) Next() ([]datasources.Sample, bool, error) {
+	frame, ok := d.splitter.Next()
+	if !ok {
+		return nil, false, nil
+	}
+	d.frame = frame
+	data, err := d.parser.ParseFrame(frame)
+	return data, true, err
+}
+
+// Line returns the text of the frame last returned by Next.
+func (d *Decoder) Line() string {
+	return d.parser.Text(d.frame)
+}
+
+// Clone returns a new decoder with the same settings and nothing buffered.
+func (d *Decoder) Clone() *Decoder {
+	return NewDecoder(d.splitter.Clone(), d.parser.Clone())
+}
+
+func (d *Decoder) FramingErrors() int {
+	return d.splitter.Errors()
+}
+
+func (d *Decoder) Reset() {
+	d.splitter.Reset()
+	d.parser.Reset()
+}
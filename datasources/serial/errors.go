@@ -4,6 +4,12 @@ type ParseError struct {
 	msg string
 }
 
+// NewParseError returns an error for data that could not be parsed, which
+// the plotter skips rather than stopping.
+func NewParseError(msg string) *ParseError {
+	return &ParseError{msg: msg}
+}
+
 func (e *ParseError) Error() string {
 	return e.msg
 }
@@ -0,0 +1,90 @@
+package serial
+
+import (
+	"fmt"
+	"time"
+)
+
+// Grammar is how lines of text are parsed.
+type Grammar int
+
+const (
+	// ArduinoGrammar parses the Arduino IDE serial plotter formats
+	ArduinoGrammar Grammar = iota
+	// NMEAGrammar parses NMEA 0183 sentences
+	NMEAGrammar
+)
+
+var grammarName = map[Grammar]string{
+	ArduinoGrammar: "Arduino",
+	NMEAGrammar:    "NMEA 0183",
+}
+
+func (g Grammar) String() string {
+	return grammarName[g]
+}
+
+func GrammarOptions() []string {
+	return []string{
+		ArduinoGrammar.String(),
+		NMEAGrammar.String(),
+	}
+}
+
+func ParseGrammar(value string) (Grammar, error) {
+	for grammar, name := range grammarName {
+		if name == value {
+			return grammar, nil
+		}
+	}
+	return ArduinoGrammar, fmt.Errorf("unknown grammar (%s)", value)
+}
+
+// Format holds the settings of how a byte stream is decoded, text lines in
+// a grammar or binary records of a layout.
+type Format struct {
+	Framing    Framing
+	Terminator []byte
+	// Grammar is how lines are parsed with line framing
+	Grammar        Grammar
+	MaxLineLength  int
+	NonFinite      NonFinitePolicy
+	TimestampField string
+	TimestampUnit  time.Duration
+	// Layout is the record in each frame of a binary framing
+	Layout Layout
+}
+
+func DefaultFormat() Format {
+	return Format{
+		Framing:       LineFraming,
+		Terminator:    []byte("\n"),
+		MaxLineLength: DefaultMaxLineLength,
+		NonFinite:     DropNonFinite,
+		TimestampUnit: time.Millisecond,
+	}
+}
+
+// NewDecoder returns a decoder for the format.
+func (f Format) NewDecoder() *Decoder {
+	if f.Framing == LineFraming && f.Grammar == NMEAGrammar {
+		return NewDecoder(NewFramer(f.Terminator, f.MaxLineLength), NewNMEAParser(f.NonFinite))
+	}
+	if f.Framing == LineFraming {
+		parser := NewArduinoParser(f.NonFinite)
+		parser.SetTimestampField(f.TimestampField, f.TimestampUnit)
+		return NewDecoder(NewFramer(f.Terminator, f.MaxLineLength), parser)
+	}
+	parser := NewStructParser(f.Layout, f.NonFinite)
+	parser.SetTimestampField(f.TimestampField, f.TimestampUnit)
+	var splitter Splitter
+	switch f.Framing {
+	case COBSFraming:
+		splitter = NewCOBSSplitter()
+	case SLIPFraming:
+		splitter = NewSLIPSplitter()
+	default:
+		splitter = NewSyncSplitter()
+	}
+	return NewDecoder(splitter, parser)
+}
@@ -0,0 +1,127 @@
+package serial
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const DefaultMaxLineLength = 1024
+
+// Framer buffers raw bytes read from a port and splits them into lines on a
+// terminator. Partial lines are kept between writes so readings are never
+// split across or merged between reads.
+type Framer struct {
+	terminator    []byte
+	maxLineLength int
+	pending       []byte
+	discarding    bool
+	errors        int
+}
+
+func NewFramer(terminator []byte, maxLineLength int) *Framer {
+	if len(terminator) == 0 {
+		terminator = []byte("\n")
+	}
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
+	return &Framer{
+		terminator:    terminator,
+		maxLineLength: maxLineLength,
+	}
+}
+
+func TerminatorOptions() []string {
+	return []string{
+		`\n`,
+		`\r\n`,
+		`\r`,
+	}
+}
+
+// ParseTerminator converts a terminator option into the bytes it represents.
+// Besides the escaped options it accepts a single custom character or a hex
+// byte such as 0x03.
+func ParseTerminator(value string) ([]byte, error) {
+	switch value {
+	case `\n`:
+		return []byte("\n"), nil
+	case `\r\n`:
+		return []byte("\r\n"), nil
+	case `\r`:
+		return []byte("\r"), nil
+	}
+	if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+		b, err := strconv.ParseUint(value[2:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid terminator byte (%s)", value)
+		}
+		return []byte{byte(b)}, nil
+	}
+	if len(value) != 1 {
+		return nil, fmt.Errorf("terminator must be a single byte (%s)", value)
+	}
+	return []byte(value), nil
+}
+
+// Write appends a chunk of raw bytes to the pending buffer.
+func (f *Framer) Write(chunk []byte) {
+	f.pending = append(f.pending, chunk...)
+}
+
+// Next returns the next complete line without its terminator. Lines longer
+// than the max line length are dropped up to the following terminator and
+// counted as framing errors.
+func (f *Framer) Next() ([]byte, bool) {
+	for {
+		index := bytes.Index(f.pending, f.terminator)
+		if index < 0 {
+			if len(f.pending) > f.maxLineLength {
+				if !f.discarding {
+					f.errors++
+					f.discarding = true
+				}
+				// Keep enough of the tail to match a terminator split across writes
+				f.pending = f.pending[len(f.pending)-len(f.terminator)+1:]
+			}
+			return nil, false
+		}
+		line := f.pending[:index]
+		f.pending = f.pending[index+len(f.terminator):]
+		if f.discarding {
+			f.discarding = false
+			continue
+		}
+		if len(line) > f.maxLineLength {
+			f.errors++
+			continue
+		}
+		return bytes.Clone(line), true
+	}
+}
+
+// End marks the end of a message, such as a datagram, so a final line
+// without a terminator is still framed.
+func (f *Framer) End() {
+	if len(f.pending) > 0 {
+		f.pending = append(f.pending, f.terminator...)
+	}
+}
+
+// Errors returns the number of framing errors seen since the framer was
+// created or reset.
+func (f *Framer) Errors() int {
+	return f.errors
+}
+
+func (f *Framer) Clone() Splitter {
+	return NewFramer(f.terminator, f.maxLineLength)
+}
+
+func (f *Framer) Reset() {
+	f.pending = nil
+	f.discarding = false
+	f.errors = 0
+}
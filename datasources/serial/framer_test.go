@@ -0,0 +1,108 @@
+package serial
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFramer(t *testing.T) {
+	long := strings.Repeat("x", 12)
+	tests := []struct {
+		name          string
+		terminator    string
+		maxLineLength int
+		chunks        []string
+		end           bool
+		want          []string
+		errors        int
+	}{
+		{name: "one line per chunk", terminator: "\n", chunks: []string{"a:1\n", "a:2\n"}, want: []string{"a:1", "a:2"}},
+		{name: "lines in one chunk", terminator: "\n", chunks: []string{"a:1\na:2\na:"}, want: []string{"a:1", "a:2"}},
+		{name: "line split across chunks", terminator: "\n", chunks: []string{"a:", "1", "2\n"}, want: []string{"a:12"}},
+		{name: "empty lines", terminator: "\n", chunks: []string{"\n\na:1\n"}, want: []string{"", "", "a:1"}},
+		{name: "multi byte terminator", terminator: "\r\n", chunks: []string{"a:1\r\na:\r2\r\n"}, want: []string{"a:1", "a:\r2"}},
+		{name: "terminator split across chunks", terminator: "\r\n", chunks: []string{"a:1\r", "\na:2\r", "\n"}, want: []string{"a:1", "a:2"}},
+		{name: "custom terminator", terminator: "\x03", chunks: []string{"a:1\x03a:2\x03"}, want: []string{"a:1", "a:2"}},
+		{name: "line at max length", terminator: "\n", maxLineLength: 12, chunks: []string{long + "\n"}, want: []string{long}},
+		{name: "overlong line in one chunk", terminator: "\n", maxLineLength: 8, chunks: []string{long + "\na:1\n"}, want: []string{"a:1"}, errors: 1},
+		{name: "overlong line across chunks", terminator: "\n", maxLineLength: 8, chunks: []string{long, long, "\na:1\n"}, want: []string{"a:1"}, errors: 1},
+		{name: "overlong line before split terminator", terminator: "\r\n", maxLineLength: 8, chunks: []string{long + "\r", "\na:1\r\n"}, want: []string{"a:1"}, errors: 1},
+		{name: "end frames the last line", terminator: "\n", chunks: []string{"a:1\na:2"}, end: true, want: []string{"a:1", "a:2"}},
+		{name: "end without a partial line", terminator: "\n", chunks: []string{"a:1\n"}, end: true, want: []string{"a:1"}},
+	}
+	for _, test := range tests {
+		framer := NewFramer([]byte(test.terminator), test.maxLineLength)
+		got := []string{}
+		for _, chunk := range test.chunks {
+			framer.Write([]byte(chunk))
+			for {
+				line, ok := framer.Next()
+				if !ok {
+					break
+				}
+				got = append(got, string(line))
+			}
+		}
+		if test.end {
+			framer.End()
+			for {
+				line, ok := framer.Next()
+				if !ok {
+					break
+				}
+				got = append(got, string(line))
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: lines = %q, want %q", test.name, got, test.want)
+		}
+		if framer.Errors() != test.errors {
+			t.Errorf("%s: errors = %d, want %d", test.name, framer.Errors(), test.errors)
+		}
+	}
+}
+
+func TestFramerReset(t *testing.T) {
+	framer := NewFramer([]byte("\n"), 4)
+	framer.Write([]byte("too long"))
+	framer.Next()
+	framer.Reset()
+	if framer.Errors() != 0 {
+		t.Errorf("errors after Reset = %d, want 0", framer.Errors())
+	}
+	framer.Write([]byte("a:1\n"))
+	line, ok := framer.Next()
+	if !ok || !bytes.Equal(line, []byte("a:1")) {
+		t.Errorf("Next after Reset = %q, %v, want a:1", line, ok)
+	}
+}
+
+func TestParseTerminator(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []byte
+		wantErr bool
+	}{
+		{value: `\n`, want: []byte("\n")},
+		{value: `\r\n`, want: []byte("\r\n")},
+		{value: `\r`, want: []byte("\r")},
+		{value: ";", want: []byte(";")},
+		{value: "0x03", want: []byte{0x03}},
+		{value: "0X1f", want: []byte{0x1f}},
+		{value: "0x100", wantErr: true},
+		{value: "ab", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseTerminator(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseTerminator(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !bytes.Equal(got, test.want) {
+			t.Errorf("ParseTerminator(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
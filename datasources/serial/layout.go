@@ -0,0 +1,274 @@
+package serial
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+// FieldType is the type of a field of a binary record.
+type FieldType int
+
+const (
+	U8 FieldType = iota
+	I8
+	U16
+	I16
+	U32
+	I32
+	U64
+	I64
+	F32
+	F64
+)
+
+// fieldTypeNames are the names a type can be written as in a layout, the
+// first being its canonical name.
+var fieldTypeNames = map[FieldType][]string{
+	U8:  {"u8", "uint8", "uint8_t", "byte"},
+	I8:  {"i8", "int8", "int8_t"},
+	U16: {"u16", "uint16", "uint16_t"},
+	I16: {"i16", "int16", "int16_t"},
+	U32: {"u32", "uint32", "uint32_t"},
+	I32: {"i32", "int32", "int32_t"},
+	U64: {"u64", "uint64", "uint64_t"},
+	I64: {"i64", "int64", "int64_t"},
+	F32: {"f32", "float32", "float"},
+	F64: {"f64", "float64", "double"},
+}
+
+var fieldTypeSize = map[FieldType]int{
+	U8:  1,
+	I8:  1,
+	U16: 2,
+	I16: 2,
+	U32: 4,
+	I32: 4,
+	U64: 8,
+	I64: 8,
+	F32: 4,
+	F64: 8,
+}
+
+func (t FieldType) String() string {
+	return fieldTypeNames[t][0]
+}
+
+func (t FieldType) Size() int {
+	return fieldTypeSize[t]
+}
+
+func ParseFieldType(value string) (FieldType, error) {
+	for fieldType, names := range fieldTypeNames {
+		for _, name := range names {
+			if name == value {
+				return fieldType, nil
+			}
+		}
+	}
+	return U8, fmt.Errorf("unknown field type (%s)", value)
+}
+
+// ByteOrder is the endianness of the fields of a binary record.
+type ByteOrder int
+
+const (
+	LittleEndian ByteOrder = iota
+	BigEndian
+)
+
+var byteOrderName = map[ByteOrder]string{
+	LittleEndian: "Little Endian",
+	BigEndian:    "Big Endian",
+}
+
+func (b ByteOrder) String() string {
+	return byteOrderName[b]
+}
+
+func ByteOrderOptions() []string {
+	return []string{
+		LittleEndian.String(),
+		BigEndian.String(),
+	}
+}
+
+// ParseByteOrder accepts an option name, or little or big.
+func ParseByteOrder(value string) (ByteOrder, error) {
+	for byteOrder, name := range byteOrderName {
+		if name == value || strings.EqualFold(strings.Fields(name)[0], value) {
+			return byteOrder, nil
+		}
+	}
+	return LittleEndian, fmt.Errorf("unknown byte order (%s)", value)
+}
+
+// LayoutField is a named field of a binary record. Fields named _ are
+// padding and are not plotted.
+type LayoutField struct {
+	Name string
+	Type FieldType
+}
+
+// Layout is the packed layout of a binary record.
+type Layout struct {
+	Fields []LayoutField
+	Order  ByteOrder
+}
+
+// ParseLayout parses a record layout written like packed C struct members,
+// e.g. "u32 t; i16 ax, ay, az; f32 temp". Declarations are separated by
+// semicolons or newlines.
+func ParseLayout(value string, order ByteOrder) (Layout, error) {
+	layout := Layout{Order: order}
+	declarations := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ';' || r == '\n'
+	})
+	for _, declaration := range declarations {
+		declaration = strings.TrimSpace(declaration)
+		if declaration == "" {
+			continue
+		}
+		typeName, names, ok := strings.Cut(declaration, " ")
+		if !ok {
+			return layout, fmt.Errorf("layout declaration needs a type and a name (%s)", declaration)
+		}
+		fieldType, err := ParseFieldType(typeName)
+		if err != nil {
+			return layout, err
+		}
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return layout, fmt.Errorf("layout declaration has an empty name (%s)", declaration)
+			}
+			layout.Fields = append(layout.Fields, LayoutField{Name: name, Type: fieldType})
+		}
+	}
+	if len(layout.Fields) == 0 {
+		return layout, fmt.Errorf("layout has no fields")
+	}
+	return layout, nil
+}
+
+// Size returns the number of bytes in a record.
+func (l Layout) Size() int {
+	size := 0
+	for _, field := range l.Fields {
+		size += field.Type.Size()
+	}
+	return size
+}
+
+func (l Layout) String() string {
+	declarations := []string{}
+	for _, field := range l.Fields {
+		declarations = append(declarations, field.Type.String()+" "+field.Name)
+	}
+	return strings.Join(declarations, "; ")
+}
+
+// StructParser parses binary frames holding one record of a layout. A
+// field named by the timestamp field sets the device time of the other
+// samples in the record, the same as for text lines.
+type StructParser struct {
+	layout         Layout
+	nonFinite      NonFinitePolicy
+	timestampField string
+	timestampUnit  time.Duration
+}
+
+func NewStructParser(layout Layout, nonFinite NonFinitePolicy) *StructParser {
+	return &StructParser{
+		layout:        layout,
+		nonFinite:     nonFinite,
+		timestampUnit: time.Millisecond,
+	}
+}
+
+func (p *StructParser) SetTimestampField(field string, unit time.Duration) {
+	p.timestampField = field
+	p.timestampUnit = unit
+}
+
+func (p *StructParser) ParseFrame(frame []byte) ([]datasources.Sample, error) {
+	if len(frame) != p.layout.Size() {
+		return nil, NewParseError(fmt.Sprintf("frame has %d bytes, layout (%s) needs %d", len(frame), p.layout, p.layout.Size()))
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if p.layout.Order == BigEndian {
+		order = binary.BigEndian
+	}
+	data := []datasources.Sample{}
+	var deviceTime time.Duration
+	hasDeviceTime := false
+	offset := 0
+	for _, field := range p.layout.Fields {
+		value := decodeField(field.Type, order, frame[offset:])
+		offset += field.Type.Size()
+		if field.Name == "_" {
+			continue
+		}
+		if p.timestampField != "" && field.Name == p.timestampField {
+			deviceTime = time.Duration(value * float64(p.timestampUnit))
+			hasDeviceTime = true
+			continue
+		}
+		datum, ok := p.nonFinite.apply(value)
+		if !ok {
+			continue
+		}
+		data = append(data, datasources.Sample{
+			Name:  field.Name,
+			Value: datum,
+		})
+	}
+	for i := range data {
+		data[i].DeviceTime = deviceTime
+		data[i].HasDeviceTime = hasDeviceTime
+	}
+	return data, nil
+}
+
+func decodeField(fieldType FieldType, order binary.ByteOrder, data []byte) float64 {
+	switch fieldType {
+	case U8:
+		return float64(data[0])
+	case I8:
+		return float64(int8(data[0]))
+	case U16:
+		return float64(order.Uint16(data))
+	case I16:
+		return float64(int16(order.Uint16(data)))
+	case U32:
+		return float64(order.Uint32(data))
+	case I32:
+		return float64(int32(order.Uint32(data)))
+	case U64:
+		return float64(order.Uint64(data))
+	case I64:
+		return float64(int64(order.Uint64(data)))
+	case F32:
+		return float64(math.Float32frombits(order.Uint32(data)))
+	case F64:
+		return math.Float64frombits(order.Uint64(data))
+	}
+	return math.NaN()
+}
+
+// Text shows a binary frame as hex.
+func (p *StructParser) Text(frame []byte) string {
+	return fmt.Sprintf("% X", frame)
+}
+
+func (p *StructParser) Reset() {}
+
+func (p *StructParser) Clone() FrameParser {
+	parser := NewStructParser(p.layout, p.nonFinite)
+	parser.SetTimestampField(p.timestampField, p.timestampUnit)
+	return parser
+}
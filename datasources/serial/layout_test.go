@@ -0,0 +1,138 @@
+package serial
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		size    int
+		wantErr bool
+	}{
+		{value: "u32 t; i16 ax, ay, az; f32 temp", want: "u32 t; i16 ax; i16 ay; i16 az; f32 temp", size: 14},
+		{value: "uint8_t flags\nfloat x;double y;", want: "u8 flags; f32 x; f64 y", size: 13},
+		{value: "u8 _, _; u16 raw", want: "u8 _; u8 _; u16 raw", size: 4},
+		{value: "", wantErr: true},
+		{value: "u24 x", wantErr: true},
+		{value: "f32", wantErr: true},
+		{value: "f32 x,", wantErr: true},
+	}
+	for _, test := range tests {
+		layout, err := ParseLayout(test.value, LittleEndian)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseLayout(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if layout.String() != test.want {
+			t.Errorf("ParseLayout(%q) = %q, want %q", test.value, layout, test.want)
+		}
+		if layout.Size() != test.size {
+			t.Errorf("ParseLayout(%q) size = %d, want %d", test.value, layout.Size(), test.size)
+		}
+	}
+}
+
+func TestStructParser(t *testing.T) {
+	for _, order := range []ByteOrder{LittleEndian, BigEndian} {
+		var byteOrder binary.AppendByteOrder = binary.LittleEndian
+		if order == BigEndian {
+			byteOrder = binary.BigEndian
+		}
+		frame := byteOrder.AppendUint32(nil, 1500)
+		frame = byteOrder.AppendUint16(frame, uint16(0xffff&-12))
+		frame = append(frame, 0x00)
+		frame = byteOrder.AppendUint32(frame, math.Float32bits(21.5))
+
+		layout, err := ParseLayout("u32 t; i16 ax; u8 _; f32 temp", order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser := NewStructParser(layout, DropNonFinite)
+		parser.SetTimestampField("t", time.Millisecond)
+		got, err := parser.ParseFrame(frame)
+		if err != nil {
+			t.Fatalf("%s: %v", order, err)
+		}
+		want := []datasources.Sample{
+			{Name: "ax", Value: -12, DeviceTime: 1500 * time.Millisecond, HasDeviceTime: true},
+			{Name: "temp", Value: 21.5, DeviceTime: 1500 * time.Millisecond, HasDeviceTime: true},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: samples = %v, want %v", order, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: sample %d = %v, want %v", order, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestStructParserSizeMismatch(t *testing.T) {
+	layout, err := ParseLayout("i16 x", LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewStructParser(layout, DropNonFinite).ParseFrame([]byte{0x01, 0x02, 0x03})
+	var parseError *ParseError
+	if !errors.As(err, &parseError) {
+		t.Errorf("error = %v, want a ParseError", err)
+	}
+}
+
+func TestFormatDecoder(t *testing.T) {
+	layout, err := ParseLayout("i16 x", BigEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := DefaultFormat()
+	format.Framing = SLIPFraming
+	format.Layout = layout
+	decoder := format.NewDecoder()
+	decoder.Write([]byte{0x01, 0x02, 0xc0})
+	got, ok, err := decoder.Next()
+	if !ok || err != nil {
+		t.Fatalf("Next = %v, %v, %v", got, ok, err)
+	}
+	if len(got) != 1 || got[0].Name != "x" || got[0].Value != 258 {
+		t.Errorf("samples = %v, want x 258", got)
+	}
+	if decoder.Line() != "01 02" {
+		t.Errorf("Line = %q, want \"01 02\"", decoder.Line())
+	}
+}
+
+func TestParseByteOrder(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ByteOrder
+		wantErr bool
+	}{
+		{value: "Little Endian", want: LittleEndian},
+		{value: "Big Endian", want: BigEndian},
+		{value: "little", want: LittleEndian},
+		{value: "BIG", want: BigEndian},
+		{value: "middle", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseByteOrder(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseByteOrder(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && got != test.want {
+			t.Errorf("ParseByteOrder(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
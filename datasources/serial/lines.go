@@ -0,0 +1,157 @@
+package serial
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LineAction is what is done to the DTR or RTS line when the port opens.
+type LineAction int
+
+const (
+	LineAssert LineAction = iota
+	LineDeassert
+	// LinePulse opens with the line deasserted and asserts it after the
+	// pulse duration, resetting boards that reset on DTR.
+	LinePulse
+)
+
+const pulseDuration = 100 * time.Millisecond
+
+var lineActionName = map[LineAction]string{
+	LineAssert:   "Assert",
+	LineDeassert: "Deassert",
+	LinePulse:    "Pulse",
+}
+
+func (l LineAction) String() string {
+	return lineActionName[l]
+}
+
+func LineActionOptions() []string {
+	return []string{
+		LineAssert.String(),
+		LineDeassert.String(),
+		LinePulse.String(),
+	}
+}
+
+func ParseLineAction(value string) (LineAction, error) {
+	for action, name := range lineActionName {
+		if name == value {
+			return action, nil
+		}
+	}
+	return LineAssert, fmt.Errorf("unknown line action (%s)", value)
+}
+
+func (s *SerialPort) SetLineActions(dtr LineAction, rts LineAction) {
+	s.dtr = dtr
+	s.rts = rts
+}
+
+// SetResetDiscard drops what the board sends for a duration or a number of
+// lines after it is reset, whichever lasts longer, to skip bootloader output.
+func (s *SerialPort) SetResetDiscard(duration time.Duration, lines int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.discardDuration = duration
+	s.discardLines = lines
+}
+
+// pulseLines asserts any line opened deasserted for a pulse.
+func (s *SerialPort) pulseLines(ctx context.Context) error {
+	if s.dtr != LinePulse && s.rts != LinePulse {
+		return nil
+	}
+	err := sleep(ctx, pulseDuration)
+	if err != nil {
+		return err
+	}
+	if s.dtr == LinePulse {
+		err = s.port.SetDTR(true)
+		if err != nil {
+			return err
+		}
+	}
+	if s.rts == LinePulse {
+		err = s.port.SetRTS(true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetBoard pulses DTR while the port is open. The mutex is released for
+// the pulse so the port can still be controlled or closed meanwhile.
+func (s *SerialPort) ResetBoard() error {
+	s.mutex.Lock()
+	port := s.port
+	if port == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("serial port is not open")
+	}
+	err := port.SetDTR(false)
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	time.Sleep(pulseDuration)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.port != port {
+		return fmt.Errorf("serial port was closed during reset")
+	}
+	err = port.SetDTR(true)
+	if err != nil {
+		return err
+	}
+	// Drop anything received from before the reset
+	err = port.ResetInputBuffer()
+	if err != nil {
+		return err
+	}
+	s.flushPending = true
+	s.markReset()
+	return nil
+}
+
+// markReset starts discarding after a reset, the caller holds the mutex.
+func (s *SerialPort) markReset() {
+	s.resetAt = time.Now()
+	s.discardRemaining = s.discardLines
+}
+
+// discard reports whether a line decoded now falls in the discard window.
+func (s *SerialPort) discard() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.discardRemaining > 0 {
+		s.discardRemaining--
+		return true
+	}
+	return time.Since(s.resetAt) < s.discardDuration
+}
+
+// takeFlush reports whether the board was reset since the last call, so a
+// partial line decoded from before the reset should be dropped.
+func (s *SerialPort) takeFlush() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	flush := s.flushPending
+	s.flushPending = false
+	return flush
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
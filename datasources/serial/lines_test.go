@@ -0,0 +1,91 @@
+package serial
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// fakePort records the control lines set on a port that is never opened.
+type fakePort struct {
+	serial.Port
+	mutex sync.Mutex
+	dtr   []bool
+	reset int
+}
+
+func (f *fakePort) SetDTR(dtr bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.dtr = append(f.dtr, dtr)
+	return nil
+}
+
+func (f *fakePort) ResetInputBuffer() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.reset++
+	return nil
+}
+
+func (f *fakePort) Close() error {
+	return nil
+}
+
+func TestResetBoard(t *testing.T) {
+	port := &fakePort{}
+	s := New("fake", 9600)
+	s.port = port
+	s.SetResetDiscard(time.Second, 2)
+	if err := s.ResetBoard(); err != nil {
+		t.Fatalf("ResetBoard returned error %v", err)
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(port.dtr, want) {
+		t.Errorf("DTR = %v, want %v", port.dtr, want)
+	}
+	if port.reset != 1 {
+		t.Errorf("input buffer reset %d times, want 1", port.reset)
+	}
+	if !s.takeFlush() || !s.discard() {
+		t.Errorf("reset did not flush and start discarding")
+	}
+}
+
+func TestResetBoardNotOpen(t *testing.T) {
+	if err := New("fake", 9600).ResetBoard(); err == nil {
+		t.Error("ResetBoard on a closed port returned no error")
+	}
+}
+
+func TestResetBoardClosedDuringPulse(t *testing.T) {
+	port := &fakePort{}
+	s := New("fake", 9600)
+	s.port = port
+	done := make(chan error)
+	go func() {
+		done <- s.ResetBoard()
+	}()
+	// Close waits for the mutex, so it must finish within the pulse
+	time.Sleep(pulseDuration / 4)
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(pulseDuration / 2):
+		t.Fatal("Close blocked by the reset pulse")
+	}
+	if err := <-done; err == nil {
+		t.Error("ResetBoard returned no error after the port closed")
+	}
+	port.mutex.Lock()
+	defer port.mutex.Unlock()
+	if want := []bool{false}; !reflect.DeepEqual(port.dtr, want) {
+		t.Errorf("DTR = %v, want %v", port.dtr, want)
+	}
+}
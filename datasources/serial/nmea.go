@@ -0,0 +1,249 @@
+package serial
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+// NMEAParser parses NMEA 0183 sentences from GPS receivers and marine
+// instruments. The checksum is checked when a sentence has one. Fields of
+// the sentence types in nmeaSentences are plotted as named channels, such
+// as altitude_m or wind_angle_relative_deg, whichever talker sends them.
+// Other sentences parse to no samples and are marked as unmapped in the
+// raw monitor.
+type NMEAParser struct {
+	nonFinite NonFinitePolicy
+}
+
+func NewNMEAParser(nonFinite NonFinitePolicy) *NMEAParser {
+	return &NMEAParser{nonFinite: nonFinite}
+}
+
+// nmeaValues collects the channels of a sentence, skipping empty fields as
+// instruments leave fields empty while they have no reading.
+type nmeaValues struct {
+	values []nmeaValue
+	err    error
+}
+
+type nmeaValue struct {
+	name  string
+	value float64
+}
+
+func (v *nmeaValues) add(name string, field string) {
+	if field == "" || v.err != nil {
+		return
+	}
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		v.err = fmt.Errorf("invalid %s (%s)", name, field)
+		return
+	}
+	v.values = append(v.values, nmeaValue{name: name, value: value})
+}
+
+// addCoordinate adds a latitude or longitude written as degrees and
+// minutes, ddmm.mmmm, in decimal degrees, negative to the south or west.
+func (v *nmeaValues) addCoordinate(name string, field string, hemisphere string) {
+	if field == "" || v.err != nil {
+		return
+	}
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		v.err = fmt.Errorf("invalid %s (%s)", name, field)
+		return
+	}
+	degrees := math.Trunc(value / 100)
+	value = degrees + (value-degrees*100)/60
+	if hemisphere == "S" || hemisphere == "W" {
+		value = -value
+	}
+	v.values = append(v.values, nmeaValue{name: name, value: value})
+}
+
+// nmeaSpeedUnits name the units of wind speeds.
+var nmeaSpeedUnits = map[string]string{
+	"K": "kmh",
+	"M": "ms",
+	"N": "kn",
+	"S": "mph",
+}
+
+// nmeaSentence decodes the fields, after the address, of a mapped
+// sentence type, which has at least minFields fields.
+type nmeaSentence struct {
+	minFields int
+	decode    func(fields []string, values *nmeaValues)
+}
+
+var nmeaSentences = map[string]nmeaSentence{
+	"GGA": {9, func(fields []string, values *nmeaValues) {
+		values.addCoordinate("latitude", fields[1], fields[2])
+		values.addCoordinate("longitude", fields[3], fields[4])
+		values.add("fix_quality", fields[5])
+		values.add("satellites", fields[6])
+		values.add("hdop", fields[7])
+		values.add("altitude_m", fields[8])
+	}},
+	"GLL": {4, func(fields []string, values *nmeaValues) {
+		if len(fields) > 5 && fields[5] != "A" {
+			return
+		}
+		values.addCoordinate("latitude", fields[0], fields[1])
+		values.addCoordinate("longitude", fields[2], fields[3])
+	}},
+	"RMC": {8, func(fields []string, values *nmeaValues) {
+		// A void fix repeats stale or empty readings
+		if fields[1] != "A" {
+			return
+		}
+		values.addCoordinate("latitude", fields[2], fields[3])
+		values.addCoordinate("longitude", fields[4], fields[5])
+		values.add("speed_kn", fields[6])
+		values.add("course_deg", fields[7])
+	}},
+	"VTG": {7, func(fields []string, values *nmeaValues) {
+		values.add("course_deg", fields[0])
+		values.add("speed_kn", fields[4])
+		values.add("speed_kmh", fields[6])
+	}},
+	"GSA": {17, func(fields []string, values *nmeaValues) {
+		values.add("fix_type", fields[1])
+		values.add("pdop", fields[14])
+		values.add("hdop", fields[15])
+		values.add("vdop", fields[16])
+	}},
+	"GSV": {3, func(fields []string, values *nmeaValues) {
+		values.add("satellites_in_view", fields[2])
+	}},
+	"HDT": {1, func(fields []string, values *nmeaValues) {
+		values.add("heading_deg", fields[0])
+	}},
+	"HDM": {1, func(fields []string, values *nmeaValues) {
+		values.add("heading_magnetic_deg", fields[0])
+	}},
+	"HDG": {1, func(fields []string, values *nmeaValues) {
+		values.add("heading_magnetic_deg", fields[0])
+	}},
+	"MWV": {5, func(fields []string, values *nmeaValues) {
+		if fields[4] != "A" {
+			return
+		}
+		reference := "relative"
+		if fields[1] == "T" {
+			reference = "true"
+		}
+		values.add("wind_angle_"+reference+"_deg", fields[0])
+		unit, ok := nmeaSpeedUnits[fields[3]]
+		if ok {
+			values.add("wind_speed_"+reference+"_"+unit, fields[2])
+		}
+	}},
+	"MWD": {8, func(fields []string, values *nmeaValues) {
+		values.add("wind_direction_true_deg", fields[0])
+		values.add("wind_direction_magnetic_deg", fields[2])
+		values.add("wind_speed_true_kn", fields[4])
+		values.add("wind_speed_true_ms", fields[6])
+	}},
+	"DBT": {4, func(fields []string, values *nmeaValues) {
+		values.add("depth_m", fields[2])
+	}},
+	"DPT": {1, func(fields []string, values *nmeaValues) {
+		values.add("depth_m", fields[0])
+	}},
+	"MTW": {1, func(fields []string, values *nmeaValues) {
+		values.add("water_temp_c", fields[0])
+	}},
+	"XDR": {0, func(fields []string, values *nmeaValues) {
+		// Repeated groups of type, value, unit and transducer name
+		for i := 0; i+3 < len(fields); i += 4 {
+			name := fields[i+3]
+			if name == "" {
+				name = fields[i] + strconv.Itoa(i/4+1)
+			}
+			values.add(name, fields[i+1])
+		}
+	}},
+}
+
+// splitSentence checks a sentence's checksum and splits it into its
+// sentence type and fields.
+func splitSentence(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") && !strings.HasPrefix(line, "!") {
+		return "", nil, fmt.Errorf("not an nmea sentence (%s)", line)
+	}
+	body := line[1:]
+	if index := strings.LastIndexByte(body, '*'); index >= 0 {
+		checksum, err := strconv.ParseUint(body[index+1:], 16, 8)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid nmea checksum (%s)", line)
+		}
+		body = body[:index]
+		sum := byte(0)
+		for i := 0; i < len(body); i++ {
+			sum ^= body[i]
+		}
+		if sum != byte(checksum) {
+			return "", nil, fmt.Errorf("nmea checksum %02X does not match %02X (%s)", checksum, sum, line)
+		}
+	}
+	fields := strings.Split(body, ",")
+	address := fields[0]
+	// Proprietary sentences start with P and have no talker
+	if len(address) != 5 || strings.HasPrefix(address, "P") {
+		return address, fields[1:], nil
+	}
+	return address[2:], fields[1:], nil
+}
+
+func (p *NMEAParser) ParseFrame(frame []byte) ([]datasources.Sample, error) {
+	sentenceType, fields, err := splitSentence(string(frame))
+	if err != nil {
+		return nil, NewParseError(err.Error())
+	}
+	sentence, ok := nmeaSentences[sentenceType]
+	if !ok {
+		return []datasources.Sample{}, nil
+	}
+	if len(fields) < sentence.minFields {
+		return nil, NewParseError(fmt.Sprintf("nmea %s sentence has %d fields, want %d", sentenceType, len(fields), sentence.minFields))
+	}
+	values := &nmeaValues{}
+	sentence.decode(fields, values)
+	if values.err != nil {
+		return nil, NewParseError(fmt.Sprintf("nmea %s sentence has an %s", sentenceType, values.err))
+	}
+	data := []datasources.Sample{}
+	for _, value := range values.values {
+		datum, ok := p.nonFinite.apply(value.value)
+		if !ok {
+			continue
+		}
+		data = append(data, datasources.Sample{Name: value.name, Value: datum})
+	}
+	return data, nil
+}
+
+// Text returns the sentence, marking sentence types that are not plotted.
+func (p *NMEAParser) Text(frame []byte) string {
+	line := strings.TrimSpace(string(frame))
+	sentenceType, _, err := splitSentence(line)
+	if err == nil {
+		if _, ok := nmeaSentences[sentenceType]; !ok {
+			return line + " (unmapped " + sentenceType + ")"
+		}
+	}
+	return line
+}
+
+func (p *NMEAParser) Reset() {}
+
+func (p *NMEAParser) Clone() FrameParser {
+	return NewNMEAParser(p.nonFinite)
+}
@@ -0,0 +1,120 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// sentence adds the checksum to an NMEA sentence.
+func sentence(body string) string {
+	sum := byte(0)
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
+
+func TestNMEAParser(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]float64
+	}{
+		{
+			line: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47",
+			want: map[string]float64{"latitude": 48.1173, "longitude": 11.516667, "fix_quality": 1, "satellites": 8, "hdop": 0.9, "altitude_m": 545.4},
+		},
+		{
+			line: sentence("GPRMC,123519,A,4807.038,S,01131.000,W,022.4,084.4,230394,003.1,W"),
+			want: map[string]float64{"latitude": -48.1173, "longitude": -11.516667, "speed_kn": 22.4, "course_deg": 84.4},
+		},
+		{
+			line: sentence("GPRMC,123519,V,,,,,,,230394,,"),
+			want: map[string]float64{},
+		},
+		{
+			line: sentence("WIMWV,214.8,R,0.1,K,A"),
+			want: map[string]float64{"wind_angle_relative_deg": 214.8, "wind_speed_relative_kmh": 0.1},
+		},
+		{
+			line: sentence("WIMWV,90.0,T,12.5,N,V"),
+			want: map[string]float64{},
+		},
+		{
+			line: sentence("GNGSA,A,3,01,02,03,,,,,,,,,,1.8,1.0,1.5"),
+			want: map[string]float64{"fix_type": 3, "pdop": 1.8, "hdop": 1.0, "vdop": 1.5},
+		},
+		{
+			line: sentence("HEHDT,274.07,T"),
+			want: map[string]float64{"heading_deg": 274.07},
+		},
+		{
+			line: sentence("IIXDR,C,19.52,C,AirTemp,P,1.0132,B,Barometer"),
+			want: map[string]float64{"AirTemp": 19.52, "Barometer": 1.0132},
+		},
+		{
+			// Checksums are optional
+			line: "$SDDPT,3.6,0.0\r",
+			want: map[string]float64{"depth_m": 3.6},
+		},
+		{
+			line: sentence("GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00"),
+			want: map[string]float64{"satellites_in_view": 11},
+		},
+		{
+			line: sentence("GPZDA,201530.00,04,07,2002,00,00"),
+			want: map[string]float64{},
+		},
+	}
+	for _, test := range tests {
+		data, err := NewNMEAParser(DropNonFinite).ParseFrame([]byte(test.line))
+		if err != nil {
+			t.Errorf("ParseFrame(%q) error = %v", test.line, err)
+			continue
+		}
+		got := map[string]float64{}
+		for _, sample := range data {
+			got[sample.Name] = float64(sample.Value)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("ParseFrame(%q) = %v, want %v", test.line, got, test.want)
+			continue
+		}
+		for name, value := range test.want {
+			if math.Abs(got[name]-value) > 1e-4 {
+				t.Errorf("ParseFrame(%q) %s = %v, want %v", test.line, name, got[name], value)
+			}
+		}
+	}
+}
+
+func TestNMEAParserErrors(t *testing.T) {
+	lines := []string{
+		"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48",
+		"$GPGGA,123519*ZZ",
+		sentence("GPGGA,123519,4807.038,N"),
+		sentence("GPGGA,123519,4807.038,N,01131.000,E,1,eight,0.9,545.4,M,46.9,M,,"),
+		"GPGGA,123519",
+	}
+	for _, line := range lines {
+		_, err := NewNMEAParser(DropNonFinite).ParseFrame([]byte(line))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Errorf("ParseFrame(%q) error = %v, want a ParseError", line, err)
+		}
+	}
+}
+
+func TestNMEAParserText(t *testing.T) {
+	parser := NewNMEAParser(DropNonFinite)
+	unmapped := sentence("GPZDA,201530.00,04,07,2002,00,00")
+	if text := parser.Text([]byte(unmapped + "\r")); text != unmapped+" (unmapped ZDA)" {
+		t.Errorf("Text = %q, want it marked unmapped", text)
+	}
+	mapped := sentence("HEHDT,274.07,T")
+	if text := parser.Text([]byte(mapped)); strings.Contains(text, "unmapped") {
+		t.Errorf("Text = %q, want it unmarked", text)
+	}
+}
@@ -0,0 +1,140 @@
+package serial
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type NonFinitePolicy int
+
+const (
+	// DropNonFinite discards NaN and infinite readings.
+	DropNonFinite NonFinitePolicy = iota
+	// GapNonFinite passes NaN through so the plotted line is broken.
+	GapNonFinite
+	// ClampNonFinite limits infinite readings to the float32 range and drops NaN.
+	ClampNonFinite
+)
+
+var nonFinitePolicyName = map[NonFinitePolicy]string{
+	DropNonFinite:  "Drop",
+	GapNonFinite:   "Gap",
+	ClampNonFinite: "Clamp",
+}
+
+func (p NonFinitePolicy) String() string {
+	return nonFinitePolicyName[p]
+}
+
+func NonFiniteOptions() []string {
+	return []string{
+		DropNonFinite.String(),
+		GapNonFinite.String(),
+		ClampNonFinite.String(),
+	}
+}
+
+func ParseNonFinitePolicy(value string) (NonFinitePolicy, error) {
+	for policy, name := range nonFinitePolicyName {
+		if name == value {
+			return policy, nil
+		}
+	}
+	return DropNonFinite, fmt.Errorf("unknown non-finite policy (%s)", value)
+}
+
+// apply returns the value to plot for a reading and whether it should be kept.
+func (p NonFinitePolicy) apply(value float64) (float32, bool) {
+	if !math.IsNaN(value) && math.Abs(value) <= math.MaxFloat32 {
+		return float32(value), true
+	}
+	switch p {
+	case GapNonFinite:
+		return float32(math.NaN()), true
+	case ClampNonFinite:
+		if math.IsNaN(value) {
+			return 0, false
+		}
+		if value > 0 {
+			return math.MaxFloat32, true
+		}
+		return -math.MaxFloat32, true
+	}
+	return 0, false
+}
+
+// parseNumber converts a single value token as printed by a device. It
+// accepts an optional sign, decimals with an optional exponent, hex integers
+// (0x1F) and the nan, inf and ovf strings printed by Arduino's Print.
+func parseNumber(token string) (float64, error) {
+	text := strings.ToLower(strings.TrimSpace(token))
+	sign := 1.0
+	unsigned := text
+	if len(unsigned) > 0 && (unsigned[0] == '+' || unsigned[0] == '-') {
+		if unsigned[0] == '-' {
+			sign = -1
+		}
+		unsigned = unsigned[1:]
+	}
+	switch unsigned {
+	case "nan":
+		return math.NaN(), nil
+	case "inf", "infinity", "ovf":
+		return sign * math.Inf(1), nil
+	}
+	if strings.HasPrefix(unsigned, "0x") {
+		value, err := strconv.ParseUint(unsigned[2:], 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hex number (%s)", token)
+		}
+		return sign * float64(value), nil
+	}
+	if !isDecimal(unsigned) {
+		return 0, fmt.Errorf("invalid number (%s)", token)
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		// Out of range values are reported as infinities by ParseFloat
+		if numErr, ok := err.(*strconv.NumError); !ok || numErr.Err != strconv.ErrRange {
+			return 0, fmt.Errorf("invalid number (%s)", token)
+		}
+	}
+	return value, nil
+}
+
+// isDecimal reports whether text is digits with an optional fraction and
+// exponent, such as 12, 1.5, .5, 3. or 1.2e-3.
+func isDecimal(text string) bool {
+	digits := func(i int) int {
+		start := i
+		for i < len(text) && text[i] >= '0' && text[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	i := digits(0)
+	mantissa := i
+	if i < len(text) && text[i] == '.' {
+		i++
+		fraction := digits(i)
+		i += fraction
+		mantissa += fraction
+	}
+	if mantissa == 0 {
+		return false
+	}
+	if i < len(text) && text[i] == 'e' {
+		i++
+		if i < len(text) && (text[i] == '+' || text[i] == '-') {
+			i++
+		}
+		exponent := digits(i)
+		if exponent == 0 {
+			return false
+		}
+		i += exponent
+	}
+	return i == len(text)
+}
@@ -0,0 +1,123 @@
+package serial
+
+import (
+	"math"
+	"testing"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		token   string
+		want    float64
+		wantErr bool
+	}{
+		{token: "12", want: 12},
+		{token: "-12", want: -12},
+		{token: "+3.5", want: 3.5},
+		{token: ".5", want: 0.5},
+		{token: "3.", want: 3},
+		{token: "1.2e-3", want: 1.2e-3},
+		{token: "-4E2", want: -400},
+		{token: "0x1F", want: 31},
+		{token: "-0x10", want: -16},
+		{token: "inf", want: math.Inf(1)},
+		{token: "-inf", want: math.Inf(-1)},
+		{token: "ovf", want: math.Inf(1)},
+		{token: "1e999", want: math.Inf(1)},
+		{token: "nan", want: math.NaN()},
+		{token: "NaN", want: math.NaN()},
+		{token: "", wantErr: true},
+		{token: "-", wantErr: true},
+		{token: "1e", wantErr: true},
+		{token: "1.2.3", wantErr: true},
+		{token: "0x", wantErr: true},
+		{token: "0x1p-2", wantErr: true},
+		{token: "1_000", wantErr: true},
+		{token: "abc", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.token, func(t *testing.T) {
+			got, err := parseNumber(test.token)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("parseNumber(%q) = %v, want error", test.token, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseNumber(%q) returned error %v", test.token, err)
+			}
+			if math.IsNaN(test.want) {
+				if !math.IsNaN(got) {
+					t.Fatalf("parseNumber(%q) = %v, want NaN", test.token, got)
+				}
+				return
+			}
+			if got != test.want {
+				t.Fatalf("parseNumber(%q) = %v, want %v", test.token, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseNonFinite(t *testing.T) {
+	nan := float32(math.NaN())
+	tests := []struct {
+		name      string
+		raw       string
+		nonFinite NonFinitePolicy
+		want      []datasources.Sample
+	}{
+		{
+			name:      "finite",
+			raw:       "Accel:-1.5e2,Temp:21.5",
+			nonFinite: DropNonFinite,
+			want:      []datasources.Sample{{Name: "Accel", Value: -150}, {Name: "Temp", Value: 21.5}},
+		},
+		{
+			name:      "drop",
+			raw:       "a:nan,b:inf,c:1",
+			nonFinite: DropNonFinite,
+			want:      []datasources.Sample{{Name: "c", Value: 1}},
+		},
+		{
+			name:      "gap",
+			raw:       "a:nan,b:-inf,c:1",
+			nonFinite: GapNonFinite,
+			want:      []datasources.Sample{{Name: "a", Value: nan}, {Name: "b", Value: nan}, {Name: "c", Value: 1}},
+		},
+		{
+			name:      "clamp",
+			raw:       "a:nan,b:-inf,c:ovf,d:1e40",
+			nonFinite: ClampNonFinite,
+			want:      []datasources.Sample{{Name: "b", Value: -math.MaxFloat32}, {Name: "c", Value: math.MaxFloat32}, {Name: "d", Value: math.MaxFloat32}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewArduinoParser(test.nonFinite).Parse(test.raw)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error %v", test.raw, err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("Parse(%q) = %v, want %v", test.raw, got, test.want)
+			}
+			for i := range got {
+				sameValue := got[i].Value == test.want[i].Value ||
+					(math.IsNaN(float64(got[i].Value)) && math.IsNaN(float64(test.want[i].Value)))
+				if got[i].Name != test.want[i].Name || !sameValue {
+					t.Fatalf("Parse(%q) = %v, want %v", test.raw, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalidValue(t *testing.T) {
+	_, err := NewArduinoParser(DropNonFinite).Parse("a:1.2.3")
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package serial
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+// ArduinoParser parses lines in the Arduino IDE serial plotter grammar.
+// Values are separated by spaces, tabs or commas and may be labelled with
+// name:value. Unlabelled columns are named value1, value2, ... by position
+// unless a header line of bare names has been received. A header is only
+// taken before the first data line, or later with as many names as the
+// unlabelled data has columns, so a status message such as "calibrating
+// sensors" after a reset fails to parse rather than renaming every
+// channel. A column named by the timestamp field is not plotted and instead
+// sets the device time of the other samples on the line.
+type ArduinoParser struct {
+	nonFinite NonFinitePolicy
+	labels    []string
+	// columns is the field count of the last data line, 0 before any data
+	// and -1 when it was fully labelled
+	columns        int
+	timestampField string
+	timestampUnit  time.Duration
+}
+
+var timestampUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+}
+
+func TimestampUnitOptions() []string {
+	return []string{"s", "ms", "us"}
+}
+
+func ParseTimestampUnit(value string) (time.Duration, error) {
+	unit, ok := timestampUnits[value]
+	if !ok {
+		return 0, fmt.Errorf("unknown timestamp unit (%s)", value)
+	}
+	return unit, nil
+}
+
+func NewArduinoParser(nonFinite NonFinitePolicy) *ArduinoParser {
+	return &ArduinoParser{
+		nonFinite:     nonFinite,
+		timestampUnit: time.Millisecond,
+	}
+}
+
+// SetTimestampField sets the column carrying the device timestamp, in the
+// given unit. An empty field disables device timestamps.
+func (p *ArduinoParser) SetTimestampField(field string, unit time.Duration) {
+	p.timestampField = field
+	p.timestampUnit = unit
+}
+
+func (p *ArduinoParser) SetNonFinitePolicy(nonFinite NonFinitePolicy) {
+	p.nonFinite = nonFinite
+}
+
+// Reset forgets any labels taken from a header line and accepts a new
+// header before the next data line.
+func (p *ArduinoParser) Reset() {
+	p.labels = nil
+	p.columns = 0
+}
+
+// ParseFrame parses a line framed by a Framer.
+func (p *ArduinoParser) ParseFrame(frame []byte) ([]datasources.Sample, error) {
+	return p.Parse(string(frame))
+}
+
+func (p *ArduinoParser) Text(frame []byte) string {
+	return string(frame)
+}
+
+func (p *ArduinoParser) Clone() FrameParser {
+	parser := NewArduinoParser(p.nonFinite)
+	parser.SetTimestampField(p.timestampField, p.timestampUnit)
+	return parser
+}
+
+func splitFields(line string) []string {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '\r' || r == '\n'
+	})
+	// Rejoin "name: value" where the separator followed the colon
+	joined := []string{}
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.HasSuffix(field, ":") && i+1 < len(fields) && !strings.Contains(fields[i+1], ":") {
+			field += fields[i+1]
+			i++
+		}
+		joined = append(joined, field)
+	}
+	return joined
+}
+
+func (p *ArduinoParser) columnName(column int) string {
+	if column < len(p.labels) {
+		return p.labels[column]
+	}
+	return "value" + strconv.Itoa(column+1)
+}
+
+func (p *ArduinoParser) Parse(line string) ([]datasources.Sample, error) {
+	fields := splitFields(line)
+	if len(fields) == 0 {
+		return nil, &ParseError{
+			msg: fmt.Sprintf("no match found for (%s)", line),
+		}
+	}
+	if p.isHeader(fields) {
+		if p.columns != 0 && len(fields) != p.columns {
+			return nil, &ParseError{
+				msg: fmt.Sprintf("text line (%s) is not a header for the data", line),
+			}
+		}
+		p.labels = fields
+		return []datasources.Sample{}, nil
+	}
+	data := []datasources.Sample{}
+	var deviceTime time.Duration
+	hasDeviceTime := false
+	labelled := true
+	for column, field := range fields {
+		name := p.columnName(column)
+		value := field
+		index := strings.LastIndex(field, ":")
+		if index < 0 {
+			labelled = false
+		} else {
+			name = strings.TrimSpace(field[:index])
+			value = field[index+1:]
+		}
+		number, err := parseNumber(value)
+		if err != nil || name == "" {
+			return nil, &ParseError{
+				msg: fmt.Sprintf("could not convert field (%s) of (%s) to float", field, line),
+			}
+		}
+		if p.timestampField != "" && name == p.timestampField {
+			deviceTime = time.Duration(number * float64(p.timestampUnit))
+			hasDeviceTime = true
+			continue
+		}
+		datum, ok := p.nonFinite.apply(number)
+		if !ok {
+			continue
+		}
+		data = append(data, datasources.Sample{
+			Name:  name,
+			Value: datum,
+		})
+	}
+	for i := range data {
+		data[i].DeviceTime = deviceTime
+		data[i].HasDeviceTime = hasDeviceTime
+	}
+	p.columns = len(fields)
+	if labelled {
+		p.columns = -1
+	}
+	return data, nil
+}
+
+// isHeader reports whether every field is a bare, non-numeric name.
+func (p *ArduinoParser) isHeader(fields []string) bool {
+	for _, field := range fields {
+		if strings.Contains(field, ":") {
+			return false
+		}
+		if _, err := parseNumber(field); err == nil {
+			return false
+		}
+	}
+	return true
+}
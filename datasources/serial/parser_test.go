@@ -0,0 +1,142 @@
+package serial
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func TestArduinoParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		want    []datasources.Sample
+		wantErr bool
+	}{
+		{
+			name:  "labelled",
+			lines: []string{"RandomWalk:12,Ramp:3"},
+			want:  []datasources.Sample{{Name: "RandomWalk", Value: 12}, {Name: "Ramp", Value: 3}},
+		},
+		{
+			name:  "labelled with spaces",
+			lines: []string{"a: 1 b: 2"},
+			want:  []datasources.Sample{{Name: "a", Value: 1}, {Name: "b", Value: 2}},
+		},
+		{
+			name:  "space separated",
+			lines: []string{"12 34 56"},
+			want:  []datasources.Sample{{Name: "value1", Value: 12}, {Name: "value2", Value: 34}, {Name: "value3", Value: 56}},
+		},
+		{
+			name:  "comma separated",
+			lines: []string{"12,34,56\r"},
+			want:  []datasources.Sample{{Name: "value1", Value: 12}, {Name: "value2", Value: 34}, {Name: "value3", Value: 56}},
+		},
+		{
+			name:  "tab separated",
+			lines: []string{"12\t-34"},
+			want:  []datasources.Sample{{Name: "value1", Value: 12}, {Name: "value2", Value: -34}},
+		},
+		{
+			name:  "mixed",
+			lines: []string{"temp:21.5 40"},
+			want:  []datasources.Sample{{Name: "temp", Value: 21.5}, {Name: "value2", Value: 40}},
+		},
+		{
+			name:  "header line",
+			lines: []string{"Temp Humidity", "21 40"},
+			want:  []datasources.Sample{{Name: "Temp", Value: 21}, {Name: "Humidity", Value: 40}},
+		},
+		{
+			name:    "status line after data",
+			lines:   []string{"Temp Humidity", "21 40", "calibrating sensors now"},
+			wantErr: true,
+		},
+		{
+			name:    "status line after labelled data",
+			lines:   []string{"temp:21", "calibrating"},
+			wantErr: true,
+		},
+		{
+			name:  "status line keeps labels",
+			lines: []string{"Temp Humidity", "21 40", "calibrating sensors now", "22 41"},
+			want:  []datasources.Sample{{Name: "Temp", Value: 22}, {Name: "Humidity", Value: 41}},
+		},
+		{
+			name:  "header repeated after data",
+			lines: []string{"21 40", "Temp Humidity", "22 41"},
+			want:  []datasources.Sample{{Name: "Temp", Value: 22}, {Name: "Humidity", Value: 41}},
+		},
+		{
+			name:    "empty",
+			lines:   []string{" \r"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			lines:   []string{"12 abc:x"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := NewArduinoParser(DropNonFinite)
+			var got []datasources.Sample
+			var err error
+			for _, line := range test.lines {
+				got, err = parser.Parse(line)
+			}
+			if test.wantErr {
+				if _, ok := err.(*ParseError); !ok {
+					t.Fatalf("expected ParseError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse returned error %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("Parse = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestArduinoParserTimestamp(t *testing.T) {
+	parser := NewArduinoParser(DropNonFinite)
+	parser.SetTimestampField("t", time.Millisecond)
+	got, err := parser.Parse("t:1500,temp:21.5,rh:40")
+	if err != nil {
+		t.Fatalf("Parse returned error %v", err)
+	}
+	want := []datasources.Sample{
+		{Name: "temp", Value: 21.5, DeviceTime: 1500 * time.Millisecond, HasDeviceTime: true},
+		{Name: "rh", Value: 40, DeviceTime: 1500 * time.Millisecond, HasDeviceTime: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse = %v, want %v", got, want)
+	}
+}
+
+func TestArduinoParserResetAcceptsHeader(t *testing.T) {
+	parser := NewArduinoParser(DropNonFinite)
+	for _, line := range []string{"a:1", "Temp Humidity"} {
+		parser.Parse(line)
+	}
+	parser.Reset()
+	_, err := parser.Parse("Temp Humidity Pressure")
+	if err != nil {
+		t.Fatalf("Parse of a header after Reset returned error %v", err)
+	}
+	got, err := parser.Parse("21 40 1013")
+	if err != nil {
+		t.Fatalf("Parse returned error %v", err)
+	}
+	want := []datasources.Sample{{Name: "Temp", Value: 21}, {Name: "Humidity", Value: 40}, {Name: "Pressure", Value: 1013}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package serial
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// DefaultPortPollInterval is how often WatchPorts checks for plugged and
+// unplugged ports.
+const DefaultPortPollInterval = time.Second
+
+// PortInfo describes a serial port and, for USB ports, the device behind it.
+type PortInfo struct {
+	Name         string
+	IsUSB        bool
+	VID          string
+	PID          string
+	Manufacturer string
+	Product      string
+	SerialNumber string
+}
+
+// String labels the port with its USB details so identical boards can be
+// told apart, e.g. "/dev/ttyACM0 Arduino Uno (2341:0043) SN 85736323".
+func (p PortInfo) String() string {
+	if !p.IsUSB {
+		return p.Name
+	}
+	parts := []string{p.Name}
+	description := strings.TrimSpace(p.Manufacturer + " " + p.Product)
+	if description != "" {
+		parts = append(parts, description)
+	}
+	parts = append(parts, fmt.Sprintf("(%s:%s)", strings.ToLower(p.VID), strings.ToLower(p.PID)))
+	if p.SerialNumber != "" {
+		parts = append(parts, "SN "+p.SerialNumber)
+	}
+	return strings.Join(parts, " ")
+}
+
+// GetPortDetails lists the serial ports with their USB metadata. If the
+// details can't be read the ports are still listed by name.
+func GetPortDetails() ([]PortInfo, error) {
+	details, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		fmt.Println("failed to get port details", err)
+		portNames, err := serial.GetPortsList()
+		if err != nil {
+			return []PortInfo{}, err
+		}
+		ports := []PortInfo{}
+		for _, portName := range portNames {
+			ports = append(ports, PortInfo{Name: portName})
+		}
+		return ports, nil
+	}
+	ports := []PortInfo{}
+	for _, detail := range details {
+		port := PortInfo{
+			Name:         detail.Name,
+			IsUSB:        detail.IsUSB,
+			VID:          detail.VID,
+			PID:          detail.PID,
+			Product:      detail.Product,
+			SerialNumber: detail.SerialNumber,
+		}
+		if port.IsUSB {
+			manufacturer, product := usbStrings(port.Name)
+			port.Manufacturer = manufacturer
+			if product != "" {
+				port.Product = product
+			}
+		}
+		ports = append(ports, port)
+	}
+	slices.SortFunc(ports, func(a, b PortInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ports, nil
+}
+
+// WatchPorts polls the port list every interval until ctx is cancelled,
+// calling onChange with the new list whenever a port is plugged or
+// unplugged.
+func WatchPorts(ctx context.Context, interval time.Duration, onChange func(ports []PortInfo)) {
+	var last []PortInfo
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ports, err := GetPortDetails()
+		if err != nil {
+			fmt.Println("failed to get ports", err)
+		} else if last == nil || !slices.Equal(ports, last) {
+			last = ports
+			onChange(ports)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
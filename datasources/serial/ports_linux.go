@@ -0,0 +1,32 @@
+package serial
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// usbStrings reads the manufacturer and product strings of a USB serial
+// port from sysfs, which the enumerator leaves out on Linux.
+func usbStrings(portName string) (string, string) {
+	devicePath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/tty", filepath.Base(portName), "device"))
+	if err != nil {
+		return "", ""
+	}
+	// The USB device is the nearest parent with an idVendor attribute, one
+	// level up for CDC ACM ports and two for USB serial adapters.
+	for path := devicePath; path != "/" && path != "."; path = filepath.Dir(path) {
+		if _, err := os.Stat(filepath.Join(path, "idVendor")); err == nil {
+			return readAttribute(path, "manufacturer"), readAttribute(path, "product")
+		}
+	}
+	return "", ""
+}
+
+func readAttribute(path string, name string) string {
+	data, err := os.ReadFile(filepath.Join(path, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
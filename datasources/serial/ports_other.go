@@ -0,0 +1,9 @@
+//go:build !linux
+
+package serial
+
+// usbStrings returns no strings where the enumerator already reports the
+// USB product.
+func usbStrings(portName string) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,26 @@
+package serial
+
+import "testing"
+
+func TestPortInfoString(t *testing.T) {
+	tests := []struct {
+		port PortInfo
+		want string
+	}{
+		{port: PortInfo{Name: "/dev/ttyS0"}, want: "/dev/ttyS0"},
+		{
+			port: PortInfo{Name: "/dev/ttyACM0", IsUSB: true, VID: "2341", PID: "0043", Manufacturer: "Arduino (www.arduino.cc)", Product: "Uno", SerialNumber: "85736323"},
+			want: "/dev/ttyACM0 Arduino (www.arduino.cc) Uno (2341:0043) SN 85736323",
+		},
+		{
+			port: PortInfo{Name: "COM3", IsUSB: true, VID: "10C4", PID: "EA60"},
+			want: "COM3 (10c4:ea60)",
+		},
+	}
+	for _, test := range tests {
+		got := test.port.String()
+		if got != test.want {
+			t.Errorf("%+v String() = %q, want %q", test.port, got, test.want)
+		}
+	}
+}
@@ -1,18 +1,49 @@
 package serial
 
 import (
+	"context"
 	"fmt"
-	"regexp"
-	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/taylorcoons/serial-plotter/capture"
+	"github.com/taylorcoons/serial-plotter/datasources"
 	"go.bug.st/serial"
 )
 
+// readTimeout bounds how long a read blocks before the context is checked
+const readTimeout = 100 * time.Millisecond
+
 type SerialPort struct {
 	portName string
 	baud     int
+	autoBaud bool
+	config   Config
 	port     serial.Port
 	buff     []byte
+	format   Format
+	decoder  *Decoder
+	// framingErrors is the decoder's count, kept for reading while the
+	// port is read
+	framingErrors atomic.Int64
+	capture       atomic.Pointer[capture.Writer]
+	monitor       func(line datasources.RawLine)
+	dtr           LineAction
+	rts           LineAction
+	// xoff is set while the device has paused transmission with XOFF
+	xoff atomic.Bool
+	// writeMutex keeps writes from interleaving, it is separate from mutex
+	// so a write waiting on flow control doesn't hold up reading
+	writeMutex sync.Mutex
+	// mutex guards the port against control from other goroutines and the
+	// reset discard state below
+	mutex            sync.Mutex
+	discardDuration  time.Duration
+	discardLines     int
+	resetAt          time.Time
+	discardRemaining int
+	flushPending     bool
 }
 
 func GetPorts() ([]string, error) {
@@ -48,19 +79,61 @@ func BaudOptions() []string {
 	}
 }
 
-func (s *SerialPort) OpenPort() error {
-	mode := &serial.Mode{
-		BaudRate: s.baud,
-		Parity:   serial.NoParity,
-		DataBits: 8,
-		StopBits: serial.OneStopBit,
+func (s *SerialPort) Open(ctx context.Context) error {
+	err := s.config.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid serial settings: %w", err)
+	}
+	if s.config.FlowControl == RTSCTSFlowControl && s.rts != LineAssert {
+		return fmt.Errorf("invalid serial settings: RTS must be asserted for RTS/CTS flow control")
+	}
+	if s.format.Framing != LineFraming {
+		err = s.config.checkBinaryFlowControl(s.format.Framing.String() + " framing")
+		if err != nil {
+			return fmt.Errorf("invalid serial settings: %w", err)
+		}
+	}
+	mode := s.config.mode(s.baud)
+	// Leave the lines as the OS opens them, asserted, unless told otherwise
+	if s.dtr != LineAssert || s.rts != LineAssert {
+		mode.InitialStatusBits = &serial.ModemOutputBits{
+			DTR: s.dtr == LineAssert,
+			RTS: s.rts == LineAssert,
+		}
 	}
 	port, err := serial.Open(s.portName, mode)
 	if err != nil {
 		fmt.Println("error opening port: ", err)
 		return err
 	}
+	err = port.SetReadTimeout(readTimeout)
+	if err != nil {
+		port.Close()
+		fmt.Println("error setting port read timeout: ", err)
+		return err
+	}
+	s.mutex.Lock()
 	s.port = port
+	s.mutex.Unlock()
+	s.xoff.Store(false)
+	err = s.pulseLines(ctx)
+	if err != nil {
+		s.Close()
+		return fmt.Errorf("failed to pulse lines: %w", err)
+	}
+	if s.autoBaud {
+		baud, err := s.detectBaud(ctx, baudCandidates())
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("failed to detect baud: %w", err)
+		}
+		s.baud = baud
+	}
+	s.decoder.Reset()
+	// Opening the port resets most boards
+	s.mutex.Lock()
+	s.markReset()
+	s.mutex.Unlock()
 	return nil
 }
 
@@ -70,67 +143,217 @@ func (s *SerialPort) SetPortName(portName string) {
 
 func (s *SerialPort) SetBaud(baud int) {
 	s.baud = baud
+	s.autoBaud = false
 }
 
-func (s *SerialPort) readPort(data []byte) (int, error) {
-	n, err := s.port.Read(data)
-	if err != nil {
-		return 0, err
-	}
-	return n, nil
+// SetAutoBaud detects the baud rate each time the port is opened.
+func (s *SerialPort) SetAutoBaud() {
+	s.autoBaud = true
+}
+
+func (s *SerialPort) AutoBaud() bool {
+	return s.autoBaud
+}
+
+// Baud returns the baud rate, after the port is opened this is the detected
+// rate when detecting.
+func (s *SerialPort) Baud() int {
+	return s.baud
+}
+
+func (s *SerialPort) SetConfig(config Config) {
+	s.config = config
+}
+
+func (s *SerialPort) Config() Config {
+	return s.config
+}
+
+func (s *SerialPort) SetTerminator(terminator []byte) {
+	s.format.Terminator = terminator
+	s.decoder = s.format.NewDecoder()
+}
+
+func (s *SerialPort) SetMaxLineLength(maxLineLength int) {
+	s.format.MaxLineLength = maxLineLength
+	s.decoder = s.format.NewDecoder()
+}
+
+func (s *SerialPort) SetNonFinitePolicy(nonFinite NonFinitePolicy) {
+	s.format.NonFinite = nonFinite
+	s.decoder = s.format.NewDecoder()
+}
+
+func (s *SerialPort) SetTimestampField(field string, unit time.Duration) {
+	s.format.TimestampField = field
+	s.format.TimestampUnit = unit
+	s.decoder = s.format.NewDecoder()
+}
+
+// SetGrammar sets how lines are parsed with line framing.
+func (s *SerialPort) SetGrammar(grammar Grammar) {
+	s.format.Grammar = grammar
+	s.decoder = s.format.NewDecoder()
+}
+
+// SetFraming switches between text lines and binary records of layout,
+// which is ignored for line framing. Open rejects binary framing with
+// XON/XOFF flow control.
+func (s *SerialPort) SetFraming(framing Framing, layout Layout) {
+	s.format.Framing = framing
+	s.format.Layout = layout
+	s.decoder = s.format.NewDecoder()
+}
+
+// SetCapture tees every chunk read from the port to a capture, nil stops
+// capturing.
+func (s *SerialPort) SetCapture(writer *capture.Writer) {
+	s.capture.Store(writer)
+}
+
+// NewDecoder returns a decoder with the port's current framing and parsing
+// settings, for decoding captures the same way the port would.
+func (s *SerialPort) NewDecoder() *Decoder {
+	return s.format.NewDecoder()
 }
 
-func parseData(raw string) (float32, error) {
-	expression := regexp.MustCompile(`\s*(?P<name>[^:]+):\s*(?P<value>[\d\.]+)`)
-	match := expression.FindStringSubmatch(raw)
-	if match == nil {
-		return 0, &ParseError{
-			msg: fmt.Sprintf("no match found for (%s)", raw),
+// FramingErrors returns the number of frames dropped since the port was
+// opened, such as overlong lines and binary frames failing their CRC.
+func (s *SerialPort) FramingErrors() int {
+	return int(s.framingErrors.Load())
+}
+
+func (s *SerialPort) readPort(ctx context.Context, data []byte) (int, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
 		}
-	}
-	result := make(map[string]string)
-	for i, name := range expression.SubexpNames() {
-		if i != 0 && name != "" {
-			result[name] = match[i]
+		n, err := s.port.Read(data)
+		if err != nil {
+			return 0, err
 		}
-	}
-	datum, err := strconv.ParseFloat(result["value"], 32)
-	if err != nil {
-		return 0, &ParseError{
-			msg: fmt.Sprintf("could not convert match (%s) to float", raw),
+		// A zero length read means the read timed out
+		if n == 0 {
+			continue
+		}
+		if writer := s.capture.Load(); writer != nil {
+			err := writer.Write(time.Now(), data[:n])
+			if err != nil {
+				fmt.Println("failed to write capture", err)
+			}
+		}
+		if s.config.FlowControl == XONXOFFFlowControl {
+			var last byte
+			n, last = stripFlowControl(data[:n])
+			if last != 0 {
+				s.xoff.Store(last == xoff)
+			}
+			if n == 0 {
+				continue
+			}
 		}
+		return n, nil
 	}
-	return float32(datum), nil
+}
 
+// ReadBytes reads raw bytes from the open port for protocols that aren't
+// sent as lines, such as Modbus RTU. It blocks until bytes arrive or ctx is
+// done and must not be mixed with Read.
+func (s *SerialPort) ReadBytes(ctx context.Context, data []byte) (int, error) {
+	return s.readPort(ctx, data)
+}
+
+// CheckRawSettings rejects settings that only suit text lines, for a
+// protocol reading the port with ReadBytes. Auto baud waits for lines of
+// text that a polled device never sends first, and XON/XOFF strips 0x11 and
+// 0x13 data bytes out of binary frames.
+func (s *SerialPort) CheckRawSettings(protocol string) error {
+	if s.autoBaud {
+		return fmt.Errorf("%s needs a fixed baud rate, auto baud only detects text lines", protocol)
+	}
+	return s.config.checkBinaryFlowControl(protocol)
+}
+
+// checkBinaryFlowControl rejects XON/XOFF for binary frames, where 0x11 and
+// 0x13 are data rather than flow control.
+func (c Config) checkBinaryFlowControl(protocol string) error {
+	if c.FlowControl == XONXOFFFlowControl {
+		return fmt.Errorf("%s can't use XON/XOFF flow control, which removes bytes from binary frames", protocol)
+	}
+	return nil
+}
+
+// ResetInputBuffer drops bytes received but not yet read, so a request and
+// response protocol doesn't take a late or noisy reply for the next one.
+func (s *SerialPort) ResetInputBuffer() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.port == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	return s.port.ResetInputBuffer()
 }
 
 func New(portName string, baud int) *SerialPort {
 	s := &SerialPort{
 		portName: portName,
 		baud:     baud,
+		config:   DefaultConfig(),
 		buff:     make([]byte, 255),
+		format:   DefaultFormat(),
+		decoder:  DefaultFormat().NewDecoder(),
 	}
 	return s
 }
 
-func (s *SerialPort) ReadSource() (float32, error) {
-	// TODO: Figure out how to stop whatever is going on here
-	bytesRead, err := s.readPort(s.buff)
-	if err != nil {
-		fmt.Println("failed to read port", err)
-		return 0, err
-	}
-	// fmt.Println("Buff: ", string(buff[:bytesRead]))
-	datum, err := parseData(string(s.buff[:bytesRead]))
-	if err != nil {
-		fmt.Println("failed to parse data", err)
-		return 0, err
+func (s *SerialPort) Read(ctx context.Context) ([]datasources.Sample, error) {
+	for {
+		if s.takeFlush() {
+			s.decoder.Reset()
+		}
+		data, ok, err := s.decoder.Next()
+		s.framingErrors.Store(int64(s.decoder.FramingErrors()))
+		if !ok {
+			bytesRead, err := s.readPort(ctx, s.buff)
+			if err != nil {
+				fmt.Println("failed to read port", err)
+				return nil, err
+			}
+			s.decoder.Write(s.buff[:bytesRead])
+			continue
+		}
+		received := time.Now()
+		if s.monitor != nil {
+			s.monitor(datasources.RawLine{Received: received, Text: s.decoder.Line(), Err: err})
+		}
+		if s.discard() {
+			continue
+		}
+		if err != nil {
+			fmt.Println("failed to parse data", err)
+			return nil, err
+		}
+		for i := range data {
+			data[i].Received = received
+		}
+		return data, nil
 	}
-	return datum, err
+}
+
+// SetMonitor sets a function called with every line received, including
+// lines that fail to parse. Set it before the port is read.
+func (s *SerialPort) SetMonitor(monitor func(line datasources.RawLine)) {
+	s.monitor = monitor
 }
 
 func (s *SerialPort) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.port == nil {
+		return nil
+	}
 	err := s.port.Close()
+	s.port = nil
 	if err != nil {
 		fmt.Println("failed to close serial port", err)
 	}
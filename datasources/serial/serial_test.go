@@ -0,0 +1,187 @@
+//go:build linux
+
+package serial
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/internal/ptytest"
+)
+
+func TestReadChannels(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	master, slave := ptytest.Open(t)
+	defer master.Close()
+	port := New(slave, 9600)
+	if err := port.Open(ctx); err != nil {
+		t.Fatalf("Open returned error %v", err)
+	}
+	defer port.Close()
+
+	tests := []struct {
+		line string
+		want []datasources.Sample
+	}{
+		{
+			line: "RandomWalk:12.5,Ramp:3\n",
+			want: []datasources.Sample{{Name: "RandomWalk", Value: 12.5}, {Name: "Ramp", Value: 3}},
+		},
+		{
+			line: "x:1 y:-2 z:3\r\n",
+			want: []datasources.Sample{{Name: "x", Value: 1}, {Name: "y", Value: -2}, {Name: "z", Value: 3}},
+		},
+		{
+			line: "temp:21.5\n",
+			want: []datasources.Sample{{Name: "temp", Value: 21.5}},
+		},
+	}
+	for _, test := range tests {
+		master.WriteString(test.line)
+		got, err := port.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read(%q) returned error %v", test.line, err)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("Read(%q) = %v, want %v", test.line, got, test.want)
+			continue
+		}
+		for i := range test.want {
+			if got[i].Name != test.want[i].Name || got[i].Value != test.want[i].Value {
+				t.Errorf("Read(%q) sample %d = %v, want %v", test.line, i, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	master, slave := ptytest.Open(t)
+	defer master.Close()
+	port := New(slave, 9600)
+	var lines []datasources.RawLine
+	port.SetMonitor(func(line datasources.RawLine) {
+		lines = append(lines, line)
+	})
+	if err := port.Open(ctx); err != nil {
+		t.Fatalf("Open returned error %v", err)
+	}
+	defer port.Close()
+
+	tests := []struct {
+		line    string
+		text    string
+		wantErr bool
+	}{
+		// The monitor keeps a carriage return so hex shows what was sent
+		{line: "a:1,b:2\r\n", text: "a:1,b:2\r"},
+		{line: "a:oops\n", text: "a:oops", wantErr: true},
+		{line: "a:3\n", text: "a:3"},
+	}
+	for i, test := range tests {
+		master.WriteString(test.line)
+		_, err := port.Read(ctx)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Read(%q) error = %v, wantErr %t", test.line, err, test.wantErr)
+		}
+		if len(lines) != i+1 {
+			t.Fatalf("monitor got %d lines after %q, want %d", len(lines), test.line, i+1)
+		}
+		got := lines[i]
+		if got.Text != test.text || (got.Err != nil) != test.wantErr || got.Received.IsZero() {
+			t.Errorf("monitor line for %q = %+v, want %q with error %t", test.line, got, test.text, test.wantErr)
+		}
+	}
+}
+
+func TestReadCancelled(t *testing.T) {
+	master, slave := ptytest.Open(t)
+	defer master.Close()
+	port := New(slave, 9600)
+	if err := port.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned error %v", err)
+	}
+
+	// The device sends nothing, so only cancelling ends the read
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	started := time.Now()
+	_, err := port.Read(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Read error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(started); elapsed > 50*time.Millisecond+3*readTimeout {
+		t.Errorf("Read took %v to return after cancel", elapsed)
+	}
+
+	// Closing frees the port to be opened again
+	if err := port.Close(); err != nil {
+		t.Fatalf("Close returned error %v", err)
+	}
+	if err := port.Open(context.Background()); err != nil {
+		t.Fatalf("Open after Close returned error %v", err)
+	}
+	port.Close()
+}
+
+func TestBinaryFramingRejectsXONXOFF(t *testing.T) {
+	layout, err := ParseLayout("u8 x", LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, framing := range []Framing{COBSFraming, SLIPFraming, SyncFraming} {
+		port := New("/dev/null", 9600)
+		config := DefaultConfig()
+		config.FlowControl = XONXOFFFlowControl
+		port.SetConfig(config)
+		port.SetFraming(framing, layout)
+		err := port.Open(context.Background())
+		if err == nil {
+			port.Close()
+			t.Errorf("%s: Open accepted XON/XOFF flow control", framing)
+			continue
+		}
+		if !strings.Contains(err.Error(), "XON/XOFF") {
+			t.Errorf("%s: Open error = %v, want XON/XOFF rejected", framing, err)
+		}
+	}
+}
+
+func TestBinaryFramingKeepsFlowControlBytes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	master, slave := ptytest.Open(t)
+	defer master.Close()
+	layout, err := ParseLayout("u8 x; u8 y", LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := New(slave, 9600)
+	port.SetFraming(COBSFraming, layout)
+	if err := port.Open(ctx); err != nil {
+		t.Fatalf("Open returned error %v", err)
+	}
+	defer port.Close()
+
+	// COBS encoding of the XON and XOFF bytes 0x11 0x13
+	master.Write([]byte{0x03, 0x11, 0x13, 0x00})
+	data, err := port.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read returned error %v", err)
+	}
+	if len(data) != 2 || data[0].Value != 0x11 || data[1].Value != 0x13 {
+		t.Errorf("Read = %v, want x 17 and y 19", data)
+	}
+}
@@ -0,0 +1,173 @@
+package serial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"go.bug.st/serial/enumerator"
+)
+
+const (
+	minReconnectDelay = 250 * time.Millisecond
+	maxReconnectDelay = 5 * time.Second
+)
+
+// Supervisor keeps a serial port plotting through disconnects. When a read
+// fails it closes the port and retries opening it with backoff, following
+// the board by its USB serial number if it comes back under another name.
+// Once reconnected it returns a NaN sample for every channel so the gap
+// shows in the plot.
+type Supervisor struct {
+	port         *SerialPort
+	serialNumber string
+	channels     []string
+	minDelay     time.Duration
+	maxDelay     time.Duration
+	onStatus     func(status string)
+	// serialNumberOf and lookup map between port names and USB serial
+	// numbers
+	serialNumberOf func(portName string) string
+	lookup         func(serialNumber string) (string, bool)
+}
+
+func NewSupervisor(port *SerialPort) *Supervisor {
+	return &Supervisor{
+		port:           port,
+		minDelay:       minReconnectDelay,
+		maxDelay:       maxReconnectDelay,
+		onStatus:       func(string) {},
+		serialNumberOf: portSerialNumber,
+		lookup:         lookupSerialNumber,
+	}
+}
+
+// SetOnStatus sets a function called with a message when the port
+// disconnects and reconnects.
+func (s *Supervisor) SetOnStatus(onStatus func(status string)) {
+	s.onStatus = onStatus
+}
+
+func (s *Supervisor) Open(ctx context.Context) error {
+	err := s.port.Open(ctx)
+	if err != nil {
+		return err
+	}
+	s.serialNumber = s.serialNumberOf(s.port.portName)
+	s.channels = []string{}
+	return nil
+}
+
+func (s *Supervisor) Read(ctx context.Context) ([]datasources.Sample, error) {
+	data, err := s.port.Read(ctx)
+	if err == nil {
+		s.rememberChannels(data)
+		return data, nil
+	}
+	var parseError *ParseError
+	if errors.As(err, &parseError) || ctx.Err() != nil {
+		return nil, err
+	}
+	s.port.Close()
+	s.onStatus(fmt.Sprintf("Disconnected from %s, reconnecting", s.port.portName))
+	err = s.reconnect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.onStatus(fmt.Sprintf("Reconnected to %s", s.port.portName))
+	return s.gap(), nil
+}
+
+func (s *Supervisor) SetMonitor(monitor func(line datasources.RawLine)) {
+	s.port.SetMonitor(monitor)
+}
+
+func (s *Supervisor) Close() error {
+	return s.port.Close()
+}
+
+func (s *Supervisor) rememberChannels(data []datasources.Sample) {
+	for _, sample := range data {
+		known := false
+		for _, channel := range s.channels {
+			if channel == sample.Name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			s.channels = append(s.channels, sample.Name)
+		}
+	}
+}
+
+// gap returns the marker plotted between the data before and after a
+// reconnect.
+func (s *Supervisor) gap() []datasources.Sample {
+	received := time.Now()
+	gap := []datasources.Sample{}
+	for _, channel := range s.channels {
+		gap = append(gap, datasources.Sample{
+			Name:     channel,
+			Value:    float32(math.NaN()),
+			Received: received,
+		})
+	}
+	return gap
+}
+
+// reconnect retries opening the port until it succeeds or ctx is cancelled.
+func (s *Supervisor) reconnect(ctx context.Context) error {
+	delay := s.minDelay
+	for {
+		err := sleep(ctx, delay)
+		if err != nil {
+			return err
+		}
+		if s.serialNumber != "" {
+			if portName, ok := s.lookup(s.serialNumber); ok {
+				s.port.SetPortName(portName)
+			}
+		}
+		err = s.port.Open(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Println("failed to reconnect serial port", err)
+		delay = min(delay*2, s.maxDelay)
+	}
+}
+
+// portSerialNumber returns the USB serial number of a port, or an empty
+// string if it has none.
+func portSerialNumber(portName string) string {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return ""
+	}
+	for _, port := range ports {
+		if port.Name == portName && port.IsUSB {
+			return port.SerialNumber
+		}
+	}
+	return ""
+}
+
+func lookupSerialNumber(serialNumber string) (string, bool) {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return "", false
+	}
+	for _, port := range ports {
+		if port.IsUSB && port.SerialNumber == serialNumber {
+			return port.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,76 @@
+//go:build linux
+
+package serial
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/internal/ptytest"
+)
+
+func TestSupervisorReconnects(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	master, slave := ptytest.Open(t)
+	var mutex sync.Mutex
+	currentSlave := slave
+	lookedUp := ""
+
+	supervisor := NewSupervisor(New(slave, 9600))
+	supervisor.minDelay = 10 * time.Millisecond
+	supervisor.maxDelay = 50 * time.Millisecond
+	// A pty has no USB serial number so stand one in for the board
+	supervisor.serialNumberOf = func(portName string) string {
+		if portName != slave {
+			t.Errorf("serial number of %s, want %s", portName, slave)
+		}
+		return "test"
+	}
+	supervisor.lookup = func(serialNumber string) (string, bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		lookedUp = serialNumber
+		return currentSlave, true
+	}
+	if err := supervisor.Open(ctx); err != nil {
+		t.Fatalf("Open returned error %v", err)
+	}
+	defer supervisor.Close()
+
+	master.WriteString("a:1\n")
+	data, err := supervisor.Read(ctx)
+	if err != nil || len(data) != 1 || data[0].Value != 1 {
+		t.Fatalf("Read = %v, %v, want a:1", data, err)
+	}
+
+	// Unplug the board and plug it back in under another name
+	master.Close()
+	replacement, replacementSlave := ptytest.Open(t)
+	defer replacement.Close()
+	mutex.Lock()
+	currentSlave = replacementSlave
+	mutex.Unlock()
+
+	data, err = supervisor.Read(ctx)
+	if err != nil || len(data) != 1 || data[0].Name != "a" || !math.IsNaN(float64(data[0].Value)) {
+		t.Fatalf("Read = %v, %v, want gap marker for a", data, err)
+	}
+	mutex.Lock()
+	if lookedUp != "test" {
+		t.Errorf("looked up serial number %q, want \"test\"", lookedUp)
+	}
+	mutex.Unlock()
+
+	// Give the reopened port a moment before writing so nothing is flushed
+	time.Sleep(50 * time.Millisecond)
+	replacement.WriteString("a:2\n")
+	data, err = supervisor.Read(ctx)
+	if err != nil || len(data) != 1 || data[0].Value != 2 {
+		t.Fatalf("Read = %v, %v, want a:2", data, err)
+	}
+}
@@ -0,0 +1,141 @@
+package serial
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// clearToSendTimeout is how long Write waits for the device to accept data
+// when flow control is enabled.
+const clearToSendTimeout = time.Second
+
+// LineEnding is appended to each line sent to the device.
+type LineEnding int
+
+const (
+	NoLineEnding LineEnding = iota
+	LFLineEnding
+	CRLineEnding
+	CRLFLineEnding
+)
+
+var lineEndingName = map[LineEnding]string{
+	NoLineEnding:   "None",
+	LFLineEnding:   "LF",
+	CRLineEnding:   "CR",
+	CRLFLineEnding: "CRLF",
+}
+
+var lineEndingBytes = map[LineEnding]string{
+	NoLineEnding:   "",
+	LFLineEnding:   "\n",
+	CRLineEnding:   "\r",
+	CRLFLineEnding: "\r\n",
+}
+
+func (l LineEnding) String() string {
+	return lineEndingName[l]
+}
+
+// Append returns data followed by the line ending.
+func (l LineEnding) Append(data []byte) []byte {
+	return append(data, lineEndingBytes[l]...)
+}
+
+func LineEndingOptions() []string {
+	return []string{
+		NoLineEnding.String(),
+		LFLineEnding.String(),
+		CRLineEnding.String(),
+		CRLFLineEnding.String(),
+	}
+}
+
+func ParseLineEnding(value string) (LineEnding, error) {
+	for lineEnding, name := range lineEndingName {
+		if name == value {
+			return lineEnding, nil
+		}
+	}
+	return NoLineEnding, fmt.Errorf("unknown line ending (%s)", value)
+}
+
+// ParseHex parses bytes written as hex pairs such as "01 a0 ff",
+// "0x01,0xa0,0xff" or "01a0ff".
+func ParseHex(value string) ([]byte, error) {
+	digits := strings.Builder{}
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	}) {
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+		if len(field)%2 != 0 {
+			field = "0" + field
+		}
+		digits.WriteString(field)
+	}
+	data, err := hex.DecodeString(digits.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex (%s)", value)
+	}
+	return data, nil
+}
+
+// Write sends data to the open port while it is being read. With emulated
+// RTS/CTS flow control it waits for the device to assert CTS, and with
+// emulated XON/XOFF it waits while the device has sent XOFF.
+func (s *SerialPort) Write(data []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	s.mutex.Lock()
+	port, flowControl := s.port, s.config.FlowControl
+	s.mutex.Unlock()
+	if port == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	err := s.waitClearToSend(port, flowControl)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		n, err := port.Write(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// waitClearToSend waits until flow control allows sending.
+func (s *SerialPort) waitClearToSend(port serial.Port, flowControl FlowControl) error {
+	deadline := time.Now().Add(clearToSendTimeout)
+	for {
+		clear, err := s.clearToSend(port, flowControl)
+		if err != nil || clear {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("device is not ready to receive (%s)", flowControl)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *SerialPort) clearToSend(port serial.Port, flowControl FlowControl) (bool, error) {
+	switch flowControl {
+	case RTSCTSFlowControl:
+		status, err := port.GetModemStatusBits()
+		if err != nil {
+			return false, err
+		}
+		return status.CTS, nil
+	case XONXOFFFlowControl:
+		return !s.xoff.Load(), nil
+	default:
+		return true, nil
+	}
+}
@@ -0,0 +1,49 @@
+package serial
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []byte
+		wantErr bool
+	}{
+		{value: "01 a0 FF", want: []byte{0x01, 0xa0, 0xff}},
+		{value: "0x01,0xa0,0xff", want: []byte{0x01, 0xa0, 0xff}},
+		{value: "01a0ff", want: []byte{0x01, 0xa0, 0xff}},
+		{value: "1 2 f", want: []byte{0x01, 0x02, 0x0f}},
+		{value: "", want: []byte{}},
+		{value: "zz", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseHex(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseHex(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !bytes.Equal(got, test.want) {
+			t.Errorf("ParseHex(%q) = %x, want %x", test.value, got, test.want)
+		}
+	}
+}
+
+func TestLineEndingAppend(t *testing.T) {
+	tests := []struct {
+		lineEnding LineEnding
+		want       string
+	}{
+		{lineEnding: NoLineEnding, want: "gain 2"},
+		{lineEnding: LFLineEnding, want: "gain 2\n"},
+		{lineEnding: CRLineEnding, want: "gain 2\r"},
+		{lineEnding: CRLFLineEnding, want: "gain 2\r\n"},
+	}
+	for _, test := range tests {
+		got := string(test.lineEnding.Append([]byte("gain 2")))
+		if got != test.want {
+			t.Errorf("%s Append = %q, want %q", test.lineEnding, got, test.want)
+		}
+	}
+}
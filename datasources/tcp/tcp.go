@@ -0,0 +1,256 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// pollInterval bounds how long a blocked accept or read waits before
+// checking whether the context was cancelled.
+const pollInterval = 100 * time.Millisecond
+
+// Mode is whether the plotter connects to the device or waits for the
+// device to connect.
+type Mode int
+
+const (
+	Client Mode = iota
+	Listen
+)
+
+var modeName = map[Mode]string{
+	Client: "Client",
+	Listen: "Listen",
+}
+
+func (m Mode) String() string {
+	return modeName[m]
+}
+
+func ModeOptions() []string {
+	return []string{
+		Client.String(),
+		Listen.String(),
+	}
+}
+
+func ParseMode(value string) (Mode, error) {
+	for mode, name := range modeName {
+		if name == value {
+			return mode, nil
+		}
+	}
+	return Client, fmt.Errorf("unknown tcp mode (%s)", value)
+}
+
+// TCP reads plotter lines from a TCP connection, such as an ESP32 streaming
+// over Wi-Fi or a port shared with ser2net. Lines are decoded with the same
+// decoder as the serial port. In listen mode a device that disconnects can
+// connect again without restarting.
+type TCP struct {
+	mutex    sync.Mutex
+	mode     Mode
+	address  string
+	decoder  *serial.Decoder
+	monitor  func(line datasources.RawLine)
+	listener *net.TCPListener
+	conn     net.Conn
+	buff     []byte
+}
+
+func New(mode Mode, address string) *TCP {
+	return &TCP{
+		mode:    mode,
+		address: address,
+		decoder: serial.DefaultFormat().NewDecoder(),
+		buff:    make([]byte, 255),
+	}
+}
+
+func (t *TCP) SetMode(mode Mode) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.mode = mode
+}
+
+// SetAddress sets the host:port to connect to, or to listen on in listen
+// mode.
+func (t *TCP) SetAddress(address string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.address = address
+}
+
+// SetDecoder sets how the received bytes are framed and parsed.
+func (t *TCP) SetDecoder(decoder *serial.Decoder) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.decoder = decoder
+}
+
+func (t *TCP) SetMonitor(monitor func(line datasources.RawLine)) {
+	t.monitor = monitor
+}
+
+// Addr returns the address being listened on, which tells the port chosen
+// when listening on port 0.
+func (t *TCP) Addr() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.listener == nil {
+		return ""
+	}
+	return t.listener.Addr().String()
+}
+
+func (t *TCP) Open(ctx context.Context) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.decoder.Reset()
+	switch t.mode {
+	case Client:
+		dialer := net.Dialer{}
+		conn, err := dialer.DialContext(ctx, "tcp", t.address)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	case Listen:
+		config := net.ListenConfig{}
+		listener, err := config.Listen(ctx, "tcp", t.address)
+		if err != nil {
+			return err
+		}
+		t.listener = listener.(*net.TCPListener)
+	}
+	return nil
+}
+
+func (t *TCP) Read(ctx context.Context) ([]datasources.Sample, error) {
+	for {
+		data, ok, err := t.decoder.Next()
+		if !ok {
+			n, err := t.readConn(ctx)
+			if err != nil {
+				fmt.Println("failed to read tcp connection", err)
+				return nil, err
+			}
+			t.decoder.Write(t.buff[:n])
+			continue
+		}
+		received := time.Now()
+		if t.monitor != nil {
+			t.monitor(datasources.RawLine{Received: received, Text: t.decoder.Line(), Err: err})
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range data {
+			data[i].Received = received
+		}
+		return data, nil
+	}
+}
+
+// readConn reads from the connection, accepting one first in listen mode.
+func (t *TCP) readConn(ctx context.Context) (int, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		conn, err := t.connection(ctx)
+		if err != nil {
+			return 0, err
+		}
+		err = conn.SetReadDeadline(time.Now().Add(pollInterval))
+		if err != nil {
+			return 0, err
+		}
+		n, err := conn.Read(t.buff)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil && n == 0 {
+			if t.mode == Listen {
+				// Wait for the device to connect again
+				fmt.Println("tcp device disconnected", err)
+				t.closeConn()
+				t.decoder.Reset()
+				continue
+			}
+			// Not wrapped, a dropped device is an error rather than the io.EOF
+			// that ends a finite source
+			return 0, fmt.Errorf("connection to %s closed: %v", t.address, err)
+		}
+		return n, nil
+	}
+}
+
+// connection returns the open connection, waiting for a device to connect
+// in listen mode.
+func (t *TCP) connection(ctx context.Context) (net.Conn, error) {
+	t.mutex.Lock()
+	conn, listener := t.conn, t.listener
+	t.mutex.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	if listener == nil {
+		return nil, fmt.Errorf("tcp source is not open")
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := listener.SetDeadline(time.Now().Add(pollInterval))
+		if err != nil {
+			return nil, err
+		}
+		conn, err := listener.AcceptTCP()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.mutex.Lock()
+		t.conn = conn
+		t.mutex.Unlock()
+		return conn, nil
+	}
+}
+
+func (t *TCP) closeConn() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+func (t *TCP) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var err error
+	if t.conn != nil {
+		err = t.conn.Close()
+		t.conn = nil
+	}
+	if t.listener != nil {
+		err = errors.Join(err, t.listener.Close())
+		t.listener = nil
+	}
+	if err != nil {
+		fmt.Println("failed to close tcp source", err)
+	}
+	return err
+}
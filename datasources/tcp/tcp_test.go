@@ -0,0 +1,118 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("temp:21.5,humidity:40\n"))
+		time.Sleep(time.Second)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(Client, listener.Addr().String())
+	err = source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	data, err := source.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || data[0].Name != "temp" || data[0].Value != 21.5 || data[1].Name != "humidity" || data[1].Value != 40 {
+		t.Errorf("Read = %+v, want temp 21.5 and humidity 40", data)
+	}
+}
+
+func TestClientDisconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("a:1\n"))
+		conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(Client, listener.Addr().String())
+	err = source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	data, err := source.Read(ctx)
+	if err != nil || len(data) != 1 || data[0].Value != 1 {
+		t.Fatalf("Read = %+v, %v, want a 1", data, err)
+	}
+	_, err = source.Read(ctx)
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Read error = %v, want a disconnect error that isn't io.EOF", err)
+	}
+}
+
+func TestListenAcceptsReconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(Listen, "127.0.0.1:0")
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	for i, line := range []string{"a:1\n", "a:2\n"} {
+		conn, err := net.Dial("tcp", source.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte(line))
+		data, err := source.Read(ctx)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 1 || data[0].Name != "a" || data[0].Value != float32(i+1) {
+			t.Errorf("Read = %+v, want a %d", data, i+1)
+		}
+	}
+}
+
+func TestReadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := New(Listen, "127.0.0.1:0")
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = source.Read(ctx)
+	if err != context.Canceled {
+		t.Errorf("Read error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,200 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// pollInterval bounds how long a blocked read waits before checking whether
+// the context was cancelled.
+const pollInterval = 100 * time.Millisecond
+
+// maxDatagramSize is the largest UDP payload.
+const maxDatagramSize = 65535
+
+// UDP reads plotter lines from datagrams sent to a port, or to a multicast
+// group when the address is a multicast address such as 239.0.0.1:9000.
+// Each datagram holds one or more lines, the last needing no terminator.
+// A lost datagram only loses its lines, there is no connection to stall.
+//
+// With sender prefixes enabled each channel is named after the address of
+// the board that sent it, e.g. 192.168.1.20/temp, so several boards can
+// broadcast the same channel names to one plotter.
+type UDP struct {
+	mutex         sync.Mutex
+	address       string
+	prefixSenders bool
+	decoder       *serial.Decoder
+	// decoders holds a decoder cloned from decoder for each sender, so a
+	// header line from one board doesn't name another's columns
+	decoders map[string]*serial.Decoder
+	monitor  func(line datasources.RawLine)
+	conn     *net.UDPConn
+	buff     []byte
+	// sender and current are the sender and decoder of the datagram being
+	// read
+	sender  string
+	current *serial.Decoder
+}
+
+func New(address string, prefixSenders bool) *UDP {
+	return &UDP{
+		address:       address,
+		prefixSenders: prefixSenders,
+		decoder:       serial.DefaultFormat().NewDecoder(),
+		buff:          make([]byte, maxDatagramSize),
+	}
+}
+
+// SetAddress sets the host:port to bind to, a multicast address joins that
+// group.
+func (u *UDP) SetAddress(address string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.address = address
+}
+
+func (u *UDP) SetPrefixSenders(prefixSenders bool) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.prefixSenders = prefixSenders
+}
+
+// SetDecoder sets how each datagram is framed and parsed.
+func (u *UDP) SetDecoder(decoder *serial.Decoder) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.decoder = decoder
+}
+
+func (u *UDP) SetMonitor(monitor func(line datasources.RawLine)) {
+	u.monitor = monitor
+}
+
+// Addr returns the address bound to, which tells the port chosen when
+// binding to port 0.
+func (u *UDP) Addr() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.conn == nil {
+		return ""
+	}
+	return u.conn.LocalAddr().String()
+}
+
+func (u *UDP) Open(ctx context.Context) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	address, err := net.ResolveUDPAddr("udp", u.address)
+	if err != nil {
+		return err
+	}
+	if address.IP != nil && address.IP.IsMulticast() {
+		u.conn, err = net.ListenMulticastUDP("udp", nil, address)
+	} else {
+		u.conn, err = net.ListenUDP("udp", address)
+	}
+	if err != nil {
+		return err
+	}
+	u.decoders = map[string]*serial.Decoder{}
+	u.sender = ""
+	u.current = nil
+	return nil
+}
+
+func (u *UDP) Read(ctx context.Context) ([]datasources.Sample, error) {
+	for {
+		if u.current == nil {
+			err := u.readDatagram(ctx)
+			if err != nil {
+				fmt.Println("failed to read udp datagram", err)
+				return nil, err
+			}
+			continue
+		}
+		data, ok, err := u.current.Next()
+		if !ok {
+			u.current = nil
+			continue
+		}
+		received := time.Now()
+		if u.monitor != nil {
+			u.monitor(datasources.RawLine{Received: received, Text: u.current.Line(), Err: err})
+		}
+		if err != nil {
+			return nil, err
+		}
+		u.mutex.Lock()
+		prefixSenders := u.prefixSenders
+		u.mutex.Unlock()
+		for i := range data {
+			data[i].Received = received
+			if prefixSenders {
+				data[i].Name = u.sender + "/" + data[i].Name
+			}
+		}
+		return data, nil
+	}
+}
+
+// readDatagram waits for the next datagram and buffers it in the decoder of
+// its sender.
+func (u *UDP) readDatagram(ctx context.Context) error {
+	u.mutex.Lock()
+	conn := u.conn
+	u.mutex.Unlock()
+	if conn == nil {
+		return fmt.Errorf("udp source is not open")
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := conn.SetReadDeadline(time.Now().Add(pollInterval))
+		if err != nil {
+			return err
+		}
+		n, address, err := conn.ReadFromUDP(u.buff)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		u.sender = address.IP.String()
+		decoder, ok := u.decoders[u.sender]
+		if !ok {
+			u.mutex.Lock()
+			decoder = u.decoder.Clone()
+			u.mutex.Unlock()
+			u.decoders[u.sender] = decoder
+		}
+		decoder.Write(u.buff[:n])
+		decoder.End()
+		u.current = decoder
+		return nil
+	}
+}
+
+func (u *UDP) Close() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.conn == nil {
+		return nil
+	}
+	err := u.conn.Close()
+	u.conn = nil
+	if err != nil {
+		fmt.Println("failed to close udp source", err)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func TestDatagramLines(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New("127.0.0.1:0", true)
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	conn, err := net.Dial("udp", source.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The last line of a datagram needs no terminator
+	_, err = conn.Write([]byte("a:1\nb:2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []datasources.Sample{
+		{Name: "127.0.0.1/a", Value: 1},
+		{Name: "127.0.0.1/b", Value: 2},
+	}
+	for _, sample := range want {
+		data, err := source.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 1 || data[0].Name != sample.Name || data[0].Value != sample.Value {
+			t.Errorf("Read = %+v, want %s %g", data, sample.Name, sample.Value)
+		}
+	}
+}
+
+func TestReadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := New("127.0.0.1:0", false)
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = source.Read(ctx)
+	if err != context.Canceled {
+		t.Errorf("Read error = %v, want %v", err, context.Canceled)
+	}
+}
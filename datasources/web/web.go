@@ -0,0 +1,262 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/jsonmap"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+)
+
+// message is a WebSocket message or Server-Sent Event, or the error that
+// ended the stream.
+type message struct {
+	data     []byte
+	received time.Time
+	err      error
+}
+
+// Web reads JSON samples pushed over a WebSocket (ws:// or wss:// URLs) or
+// as Server-Sent Events (http:// or https:// URLs). Each message is a JSON
+// object such as {"t":1200,"temp":21.5,"rh":40}, or an array of them.
+//
+// Without a mapping every number in a message is plotted, named by its
+// path. A mapping picks out and names the channels instead. The time field,
+// if set, is the device timestamp and is not plotted.
+type Web struct {
+	mutex     sync.Mutex
+	url       string
+	mapping   jsonmap.Mapping
+	timeField jsonmap.Path
+	timeUnit  time.Duration
+	monitor   func(line datasources.RawLine)
+	conn      *websocket.Conn
+	cancel    context.CancelFunc
+	messages  chan message
+	done      chan struct{}
+}
+
+func New(url string) *Web {
+	return &Web{
+		url:      url,
+		timeUnit: time.Millisecond,
+	}
+}
+
+func (w *Web) SetURL(url string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.url = url
+}
+
+// SetMapping sets the channels picked out of each message, an empty
+// mapping plots every number.
+func (w *Web) SetMapping(mapping jsonmap.Mapping) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.mapping = mapping
+}
+
+// SetTimeField sets the path of the device timestamp in each message, in
+// units of unit. A nil path means messages carry no timestamp.
+func (w *Web) SetTimeField(path jsonmap.Path, unit time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.timeField = path
+	w.timeUnit = unit
+}
+
+func (w *Web) SetMonitor(monitor func(line datasources.RawLine)) {
+	w.monitor = monitor
+}
+
+func (w *Web) Open(ctx context.Context) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	endpoint, err := url.Parse(w.url)
+	if err != nil {
+		return err
+	}
+	w.messages = make(chan message)
+	w.done = make(chan struct{})
+	switch endpoint.Scheme {
+	case "ws", "wss":
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, w.url, nil)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		go readWebSocket(conn, w.messages, w.done)
+	case "http", "https":
+		// The stream lives until plotting stops or Close cancels it
+		streamCtx, cancel := context.WithCancel(ctx)
+		request, err := http.NewRequestWithContext(streamCtx, http.MethodGet, w.url, nil)
+		if err != nil {
+			cancel()
+			return err
+		}
+		request.Header.Set("Accept", "text/event-stream")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			cancel()
+			return err
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			cancel()
+			return fmt.Errorf("event stream returned %s", response.Status)
+		}
+		w.cancel = cancel
+		go readEvents(response, w.messages, w.done)
+	default:
+		return fmt.Errorf("unsupported url scheme (%s), use ws, wss, http or https", endpoint.Scheme)
+	}
+	return nil
+}
+
+func (w *Web) Read(ctx context.Context) ([]datasources.Sample, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-w.messages:
+		if msg.err != nil {
+			return nil, msg.err
+		}
+		data, err := w.decode(msg.data)
+		if w.monitor != nil {
+			w.monitor(datasources.RawLine{Received: msg.received, Text: string(msg.data), Err: err})
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range data {
+			data[i].Received = msg.received
+		}
+		return data, nil
+	}
+}
+
+// decode maps a message to samples.
+func (w *Web) decode(data []byte) ([]datasources.Sample, error) {
+	w.mutex.Lock()
+	mapping, timeField, timeUnit := w.mapping, w.timeField, w.timeUnit
+	w.mutex.Unlock()
+	document, err := jsonmap.Decode(data)
+	if err != nil {
+		return nil, serial.NewParseError(fmt.Sprintf("message is not JSON: %s", err))
+	}
+	documents := []any{document}
+	if array, ok := document.([]any); ok {
+		documents = array
+	}
+	samples := []datasources.Sample{}
+	for _, document := range documents {
+		var data []datasources.Sample
+		if len(mapping) > 0 {
+			data = mapping.Apply(document)
+		} else {
+			data = jsonmap.Flatten(document)
+		}
+		if timeField != nil {
+			value, ok := timeField.Lookup(document)
+			deviceTime, isNumber := jsonmap.Number(value)
+			if ok && isNumber && !math.IsInf(deviceTime, 0) && !math.IsNaN(deviceTime) {
+				kept := data[:0]
+				for _, sample := range data {
+					if len(mapping) == 0 && sample.Name == timeField.String() {
+						continue
+					}
+					sample.DeviceTime = time.Duration(deviceTime * float64(timeUnit))
+					sample.HasDeviceTime = true
+					kept = append(kept, sample)
+				}
+				data = kept
+			}
+		}
+		samples = append(samples, data...)
+	}
+	if len(samples) == 0 {
+		return nil, serial.NewParseError("message has no mapped numbers")
+	}
+	return samples, nil
+}
+
+func readWebSocket(conn *websocket.Conn, messages chan<- message, done <-chan struct{}) {
+	for {
+		_, data, err := conn.ReadMessage()
+		select {
+		case messages <- message{data: data, received: time.Now(), err: err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readEvents sends the data of each Server-Sent Event, joining multiple
+// data lines with newlines. Event types, ids and comments are ignored.
+func readEvents(response *http.Response, messages chan<- message, done <-chan struct{}) {
+	defer response.Body.Close()
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lines := []string{}
+	send := func(msg message) bool {
+		select {
+		case messages <- msg:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(lines) > 0 && !send(message{data: []byte(strings.Join(lines, "\n")), received: time.Now()}) {
+				return
+			}
+			lines = lines[:0]
+			continue
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			lines = append(lines, strings.TrimPrefix(data, " "))
+		}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = fmt.Errorf("event stream closed")
+	}
+	send(message{err: err})
+}
+
+func (w *Web) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+		if err != nil {
+			fmt.Println("failed to close websocket", err)
+			return err
+		}
+	}
+	return nil
+}
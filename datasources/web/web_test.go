@@ -0,0 +1,101 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/datasources/jsonmap"
+)
+
+func checkSamples(t *testing.T, got []datasources.Sample, want []datasources.Sample) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Read = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i].Name != want[i].Name || got[i].Value != want[i].Value || got[i].DeviceTime != want[i].DeviceTime || got[i].HasDeviceTime != want[i].HasDeviceTime {
+			t.Fatalf("Read = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"t": 1200, "temp": 21.5, "rh": 40}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New("ws" + strings.TrimPrefix(server.URL, "http"))
+	source.SetTimeField(jsonmap.Path{"t"}, time.Millisecond)
+	err := source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	data, err := source.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, data, []datasources.Sample{
+		{Name: "rh", Value: 40, DeviceTime: 1200 * time.Millisecond, HasDeviceTime: true},
+		{Name: "temp", Value: 21.5, DeviceTime: 1200 * time.Millisecond, HasDeviceTime: true},
+	})
+}
+
+func TestServerSentEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\n\n")
+		fmt.Fprint(w, "event: sample\ndata: {\"env\": {\"temp\": 21.5},\ndata: \"rh\": 40}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source := New(server.URL)
+	mapping, err := jsonmap.ParseMapping("temp=$.env.temp, humidity=rh, missing=$.nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.SetMapping(mapping)
+	err = source.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	data, err := source.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, data, []datasources.Sample{
+		{Name: "temp", Value: 21.5},
+		{Name: "humidity", Value: 40},
+	})
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	source := New("ftp://localhost/samples")
+	err := source.Open(context.Background())
+	if err == nil {
+		source.Close()
+		t.Fatal("Open succeeded, want an error for an ftp url")
+	}
+}
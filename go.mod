@@ -1,13 +1,17 @@
 module github.com/taylorcoons/serial-plotter
 
-go 1.23.0
+go 1.24.0
 
 toolchain go1.24.4
 
 require (
 	fyne.io/fyne v1.4.3
 	fyne.io/fyne/v2 v2.6.1
+	github.com/eclipse/paho.mqtt.golang v1.5.1
+	github.com/gorilla/websocket v1.5.3
 	go.bug.st/serial v1.6.4
+	golang.org/x/sys v0.36.0
+	gonum.org/v1/gonum v0.16.0
 )
 
 require (
@@ -42,9 +46,8 @@ require (
 	github.com/yuin/goldmark v1.7.8 // indirect
 	golang.org/x/example/hello v0.0.0-20250605160450-8b405629c4a5 // indirect
 	golang.org/x/image v0.25.0 // indirect
-	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/text v0.23.0 // indirect
-	gonum.org/v1/gonum v0.16.0 // indirect
+	golang.org/x/net v0.44.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,164 @@
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/taylorcoons/serial-plotter/datasources/serial"
+	"github.com/taylorcoons/serial-plotter/gui/preference"
+)
+
+const maxHistory = 100
+
+// historyEntry is an entry that steps through previously sent commands with
+// the up and down keys.
+type historyEntry struct {
+	widget.Entry
+	history  []string
+	position int
+}
+
+func newHistoryEntry() *historyEntry {
+	entry := &historyEntry{}
+	entry.ExtendBaseWidget(entry)
+	return entry
+}
+
+func (e *historyEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyUp:
+		if e.position > 0 {
+			e.position--
+			e.show(e.history[e.position])
+		}
+	case fyne.KeyDown:
+		if e.position < len(e.history)-1 {
+			e.position++
+			e.show(e.history[e.position])
+		} else if e.position < len(e.history) {
+			e.position = len(e.history)
+			e.show("")
+		}
+	default:
+		e.Entry.TypedKey(key)
+	}
+}
+
+func (e *historyEntry) show(text string) {
+	e.SetText(text)
+	e.CursorColumn = len([]rune(text))
+	e.Refresh()
+}
+
+// remember adds a sent command to the history, skipping repeats.
+func (e *historyEntry) remember(command string) {
+	if command != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != command) {
+		e.history = append(e.history, command)
+		if len(e.history) > maxHistory {
+			e.history = e.history[len(e.history)-maxHistory:]
+		}
+	}
+	e.position = len(e.history)
+}
+
+// ConsolePanel sends commands to the open serial port while it is plotting.
+// Text is sent with the selected line ending, while in hex mode the bytes
+// are sent exactly as written. Saved macros send a command with one click.
+func (a *appState) ConsolePanel() *fyne.Container {
+	commandEntry := newHistoryEntry()
+	commandEntry.PlaceHolder = "Send to serial port"
+	status := widget.NewLabel("")
+	lineEndingSelect := widget.NewSelect(serial.LineEndingOptions(), func(value string) {
+		a.app.Preferences().SetString(preference.LineEnding.String(), value)
+	})
+	lineEndingSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.LineEnding.String(), serial.LFLineEnding.String()))
+	hexCheck := widget.NewCheck("Hex", func(checked bool) {
+		a.app.Preferences().SetBool(preference.HexSend.String(), checked)
+	})
+	hexCheck.SetChecked(a.app.Preferences().BoolWithFallback(preference.HexSend.String(), false))
+	send := func(command string) {
+		var data []byte
+		if hexCheck.Checked {
+			var err error
+			data, err = serial.ParseHex(command)
+			if err != nil {
+				status.SetText(err.Error())
+				return
+			}
+		} else {
+			lineEnding, err := serial.ParseLineEnding(lineEndingSelect.Selected)
+			if err != nil {
+				fmt.Println("failed to parse line ending option", err)
+				return
+			}
+			data = lineEnding.Append([]byte(command))
+		}
+		// Writing waits for flow control, so keep it off the UI goroutine
+		go func() {
+			err := a.serialSource.Write(data)
+			fyne.Do(func() {
+				if err != nil {
+					status.SetText(fmt.Sprintf("Failed to send: %s", err))
+					return
+				}
+				status.SetText(fmt.Sprintf("Sent %d bytes", len(data)))
+			})
+		}()
+	}
+	submit := func() {
+		command := commandEntry.Text
+		commandEntry.remember(command)
+		commandEntry.SetText("")
+		send(command)
+	}
+	commandEntry.OnSubmitted = func(string) { submit() }
+	sendButton := widget.NewButton("Send", submit)
+	macros := container.NewHBox()
+	loadMacros := func() {
+		names := a.app.Preferences().StringList(preference.MacroNames.String())
+		commands := a.app.Preferences().StringList(preference.MacroCommands.String())
+		macros.RemoveAll()
+		for i := 0; i < len(names) && i < len(commands); i++ {
+			command := commands[i]
+			macros.Add(widget.NewButton(names[i], func() { send(command) }))
+		}
+	}
+	loadMacros()
+	saveMacroButton := widget.NewButton("Save Macro", func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(commandEntry.Text)
+		macroEntry := widget.NewEntry()
+		macroEntry.SetText(commandEntry.Text)
+		items := []*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Command", macroEntry),
+		}
+		dialog.ShowForm("Save Macro", "Save", "Cancel", items, func(save bool) {
+			if !save || nameEntry.Text == "" {
+				return
+			}
+			names := a.app.Preferences().StringList(preference.MacroNames.String())
+			commands := a.app.Preferences().StringList(preference.MacroCommands.String())
+			a.app.Preferences().SetStringList(preference.MacroNames.String(), append(names, nameEntry.Text))
+			a.app.Preferences().SetStringList(preference.MacroCommands.String(), append(commands, macroEntry.Text))
+			loadMacros()
+		}, a.window)
+	})
+	clearMacrosButton := widget.NewButton("Clear Macros", func() {
+		dialog.ShowConfirm("Clear Macros", "Remove all saved macros?", func(clear bool) {
+			if !clear {
+				return
+			}
+			a.app.Preferences().SetStringList(preference.MacroNames.String(), []string{})
+			a.app.Preferences().SetStringList(preference.MacroCommands.String(), []string{})
+			loadMacros()
+		}, a.window)
+	})
+	sendOptions := container.NewHBox(lineEndingSelect, hexCheck, sendButton)
+	commandRow := container.NewBorder(nil, nil, nil, sendOptions, commandEntry)
+	macroRow := container.NewBorder(nil, nil, container.NewHBox(saveMacroButton, clearMacrosButton), status, container.NewHScroll(macros))
+	return container.NewVBox(commandRow, macroRow)
+}
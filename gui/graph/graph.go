@@ -1,9 +1,9 @@
 package graph
 
 import (
+	"fmt"
 	"image/color"
 	"math"
-	"slices"
 	"strconv"
 
 	"fyne.io/fyne/v2"
@@ -16,9 +16,24 @@ type GraphStruct struct {
 	xTicks, yTicks   []*canvas.Line
 	xLabels, yLabels []*canvas.Text
 	lines            []*canvas.Line
+	legend           []*canvas.Text
+}
+
+// Point is a single plotted value, X is the time in seconds since plotting
+// started. A NaN Y leaves a gap in the line.
+type Point struct {
+	X, Y float32
+}
+
+// Series is a single named channel of data plotted as its own line.
+type Series struct {
+	Name   string
+	Points []Point
 }
 
 type axisRange struct {
+	xMin        float32
+	xMax        float32
 	min         float32
 	max         float32
 	realizedMin float32
@@ -44,7 +59,71 @@ func primaryColor() color.Color {
 	return theme.DefaultTheme().Color(theme.ColorNamePrimary, theme.VariantDark)
 }
 
-func (g *GraphStruct) render(graphContainer *fyne.Container, size fyne.Size, data []float32) {
+var seriesPalette = []color.Color{
+	color.RGBA{255, 152, 0, 255},
+	color.RGBA{76, 175, 80, 255},
+	color.RGBA{233, 30, 99, 255},
+	color.RGBA{255, 235, 59, 255},
+	color.RGBA{156, 39, 176, 255},
+	color.RGBA{0, 188, 212, 255},
+	color.RGBA{244, 67, 54, 255},
+}
+
+func seriesColor(index int) color.Color {
+	if index == 0 {
+		return primaryColor()
+	}
+	return seriesPalette[(index-1)%len(seriesPalette)]
+}
+
+// seriesSpan returns the first and last time of all series.
+func seriesSpan(data []Series) (float32, float32, bool) {
+	xMin := float32(math.Inf(1))
+	xMax := float32(math.Inf(-1))
+	found := false
+	for _, series := range data {
+		if len(series.Points) == 0 {
+			continue
+		}
+		xMin = min(xMin, series.Points[0].X)
+		xMax = max(xMax, series.Points[len(series.Points)-1].X)
+		found = true
+	}
+	return xMin, xMax, found
+}
+
+// sampleRate estimates the rate of a series in Hz from its recent points.
+func sampleRate(series Series) float32 {
+	points := series.Points[max(0, len(series.Points)-50):]
+	if len(points) < 2 {
+		return 0
+	}
+	elapsed := points[len(points)-1].X - points[0].X
+	if elapsed <= 0 {
+		return 0
+	}
+	return float32(len(points)-1) / elapsed
+}
+
+// seriesRange returns the extent of all series, skipping NaN gaps.
+func seriesRange(data []Series) (float32, float32, bool) {
+	yMin := float32(math.Inf(1))
+	yMax := float32(math.Inf(-1))
+	found := false
+	for _, series := range data {
+		for _, point := range series.Points {
+			if math.IsNaN(float64(point.Y)) {
+				continue
+			}
+			yMin = min(yMin, point.Y)
+			yMax = max(yMax, point.Y)
+			found = true
+		}
+	}
+	return yMin, yMax, found
+}
+
+func (g *GraphStruct) render(graphContainer *fyne.Container, size fyne.Size, data []Series) {
 	g.xAxis = &canvas.Line{}
 	g.yAxis = &canvas.Line{}
 	g.xAxis.StrokeWidth = 2
@@ -56,46 +135,75 @@ func (g *GraphStruct) render(graphContainer *fyne.Container, size fyne.Size, dat
 
 	g.addAxes(&size, &axisRange)
 
-	g.addXTicks(&size, &axisRange, data)
+	g.addXTicks(&size, &axisRange)
 
 	g.addYTicks(&size, &axisRange)
 
 	g.addLines(&size, &axisRange, data)
 
+	g.addLegend(&size, data)
+
 	g.addGraphObjects(graphContainer)
 }
 
 func calcMaxTextWidth(a, b float32) float32 {
 	// TODO: Make this function generic to take variadic arguments of float32
-	minText := canvas.NewText(strconv.Itoa(int(a)), foregroundColor())
-	maxText := canvas.NewText(strconv.Itoa(int(b)), foregroundColor())
+	minText := canvas.NewText(yLabelText(a), foregroundColor())
+	maxText := canvas.NewText(yLabelText(b), foregroundColor())
 	return float32(math.Max(float64(minText.MinSize().Width), float64(maxText.MinSize().Width)))
 }
 
-func (g *GraphStruct) createAxisRange(size *fyne.Size, data []float32) axisRange {
+// yLabelText formats a y tick, switching to exponent form for values too
+// large to print as an integer.
+func yLabelText(value float32) string {
+	rounded := math.Round(float64(value))
+	if math.Abs(rounded) >= 1e9 {
+		return strconv.FormatFloat(rounded, 'g', 3, 32)
+	}
+	return strconv.Itoa(int(rounded))
+}
+
+// clampFloat32 limits a value computed in float64 to the float32 range.
+func clampFloat32(value float64) float32 {
+	return float32(math.Max(-math.MaxFloat32, math.Min(math.MaxFloat32, value)))
+}
+
+func (g *GraphStruct) createAxisRange(size *fyne.Size, data []Series) axisRange {
 	yMin := float32(-10)
 	yMax := float32(10)
-	if len(data) != 0 {
-		yMin = slices.Min(data)
-		yMax = slices.Max(data)
+	if dataMin, dataMax, ok := seriesRange(data); ok {
+		// The span is taken in float64 as clamped readings at opposite ends
+		// of the float32 range overflow it
+		if span := float64(dataMax) - float64(dataMin); !math.IsInf(span, 0) && !math.IsNaN(span) {
+			yMin = dataMin
+			yMax = dataMax
+		}
+	}
+	xMin := float32(0)
+	xMax := float32(10)
+	if spanMin, spanMax, ok := seriesSpan(data); ok {
+		xMin = spanMin
+		xMax = max(spanMax, spanMin+1)
 	}
-	yMagnitude := math.Abs(float64(yMax - yMin))
-	orderMagnitude := 1
+	yMagnitude := math.Abs(float64(yMax) - float64(yMin))
+	orderMagnitude := 1.0
 	for yMagnitude/10 > 1 {
 		yMagnitude = yMagnitude / 10
 		orderMagnitude = orderMagnitude * 10
 	}
-	realizedMin := yMin - float32(orderMagnitude)/2
-	realizedMax := yMax + float32(orderMagnitude)/2
+	realizedMin := clampFloat32(float64(yMin) - orderMagnitude/2)
+	realizedMax := clampFloat32(float64(yMax) + orderMagnitude/2)
 	zeroHeight := linearMap(0, realizedMin, realizedMax, size.Height, 0)
 
 	tickSize := float32(orderMagnitude)
-	tickMin := float32(math.Round(float64(math.Round(float64(yMin/tickSize)))) * float64(tickSize))
-	tickMax := float32(math.Round(float64(math.Round(float64(yMax/tickSize)))) * float64(tickSize))
-	numTicks := int(math.Round(math.Abs(float64(yMax-yMin))/float64(orderMagnitude))) + 1
+	tickMin := clampFloat32(math.Round(float64(yMin)/orderMagnitude) * orderMagnitude)
+	tickMax := clampFloat32(math.Round(float64(yMax)/orderMagnitude) * orderMagnitude)
+	numTicks := int(math.Round(math.Abs(float64(yMax)-float64(yMin))/orderMagnitude)) + 1
 	yAxisOffset := calcMaxTextWidth(tickMin, tickMax)
 	tickLength := float32(0.0125 * math.Max(float64(size.Width), float64(size.Height)))
 	return axisRange{
+		xMin:        xMin,
+		xMax:        xMax,
 		min:         yMin,
 		max:         yMax,
 		realizedMin: realizedMin,
@@ -117,33 +225,46 @@ func (g *GraphStruct) addAxes(size *fyne.Size, axisRange *axisRange) {
 	g.yAxis.Position2 = fyne.NewPos(axisRange.yAxisOffset, size.Height)
 }
 
-func positionXLabel(index int, length int, xLabel *canvas.Text, size *fyne.Size, axisRange *axisRange) fyne.Position {
-	xPos := axisRange.yAxisOffset + float32(index)*size.Width/float32(length) + xLabel.Size().Width/2
+func xPosition(x float32, size *fyne.Size, axisRange *axisRange) float32 {
+	return axisRange.yAxisOffset + linearMap(x, axisRange.xMin, axisRange.xMax, 0, size.Width-axisRange.yAxisOffset)
+}
+
+func positionXLabel(x float32, xLabel *canvas.Text, size *fyne.Size, axisRange *axisRange) fyne.Position {
+	xPos := xPosition(x, size, axisRange) - xLabel.MinSize().Width/2
 	yPos := axisRange.zeroHeight + axisRange.tickLength/2 + 3
 	return fyne.NewPos(xPos, yPos)
 }
 
-func (g *GraphStruct) addXTicks(size *fyne.Size, axisRange *axisRange, data []float32) {
+// xTickStep picks a 1, 2 or 5 times power of ten step in seconds so labels
+// are at least 50 pixels apart.
+func xTickStep(size *fyne.Size, axisRange *axisRange) float64 {
+	span := float64(axisRange.xMax - axisRange.xMin)
+	maxTicks := math.Max(1, float64(size.Width)*0.02)
+	magnitude := math.Pow(10, math.Floor(math.Log10(span/maxTicks)))
+	for _, multiple := range []float64{1, 2, 5, 10} {
+		if span/(magnitude*multiple) <= maxTicks {
+			return magnitude * multiple
+		}
+	}
+	return magnitude * 10
+}
+
+func (g *GraphStruct) addXTicks(size *fyne.Size, axisRange *axisRange) {
 	g.xTicks = []*canvas.Line{}
 	g.xLabels = []*canvas.Text{}
-	xLabelDensity := func(data []float32, xLabelDivisor int, size *fyne.Size) float32 {
-		return float32(len(data)) / float32(xLabelDivisor) / size.Width
-	}
-	xLabelDivisor := 1
-	for xLabelDensity(data, xLabelDivisor, size) > 0.02 {
-		xLabelDivisor++
-	}
-	for index := 0; index < len(data); index += xLabelDivisor {
+	step := xTickStep(size, axisRange)
+	first := math.Ceil(float64(axisRange.xMin)/step) * step
+	for tick := first; tick <= float64(axisRange.xMax); tick += step {
+		x := float32(tick)
 		xTick := &canvas.Line{}
-		xLabel := canvas.NewText(strconv.Itoa(index), color.White)
-		xLabel.Alignment = fyne.TextAlignCenter
-		xLabel.Move(positionXLabel(index, len(data), xLabel, size, axisRange))
-		xTick.Position1 = fyne.NewPos(axisRange.yAxisOffset+float32(index)*size.Width/float32(len(data)), axisRange.zeroHeight+(axisRange.tickLength/2))
-		xTick.Position2 = fyne.NewPos(axisRange.yAxisOffset+float32(index)*size.Width/float32(len(data)), axisRange.zeroHeight-(axisRange.tickLength/2))
+		xLabel := canvas.NewText(strconv.FormatFloat(math.Round(tick/step)*step, 'f', -1, 32), color.White)
+		xLabel.Move(positionXLabel(x, xLabel, size, axisRange))
+		xTick.Position1 = fyne.NewPos(xPosition(x, size, axisRange), axisRange.zeroHeight+(axisRange.tickLength/2))
+		xTick.Position2 = fyne.NewPos(xPosition(x, size, axisRange), axisRange.zeroHeight-(axisRange.tickLength/2))
 		xTick.StrokeColor = foregroundColor()
 		xTick.StrokeWidth = 2
-		// Skip 0 label
-		if index != 0 {
+		// Skip label at the y axis
+		if x != axisRange.xMin {
 			g.xLabels = append(g.xLabels, xLabel)
 		}
 		g.xTicks = append(g.xTicks, xTick)
@@ -155,10 +276,8 @@ func (g *GraphStruct) addYTicks(size *fyne.Size, axisRange *axisRange) {
 	g.yTicks = []*canvas.Line{}
 	for index := 0; index < axisRange.numTicks; index++ {
 		yTick := &canvas.Line{}
-		tickMin := float32(math.Round(float64(math.Round(float64(axisRange.min/axisRange.tickSize)))) * float64(axisRange.tickSize))
-		tickMax := float32(math.Round(float64(math.Round(float64(axisRange.max/axisRange.tickSize)))) * float64(axisRange.tickSize))
-		yValue := linearMap(float32(index), 0, float32(axisRange.numTicks)-1, tickMin, tickMax)
-		yLabel := canvas.NewText(strconv.Itoa(int(math.Round(float64(yValue)))), color.White)
+		yValue := linearMap(float32(index), 0, float32(axisRange.numTicks)-1, axisRange.tickMin, axisRange.tickMax)
+		yLabel := canvas.NewText(yLabelText(yValue), color.White)
 		tickHeight := linearMap(yValue, axisRange.realizedMin, axisRange.realizedMax, size.Height, 0)
 		yLabel.Move(fyne.NewPos(0, tickHeight-yLabel.MinSize().Height/2))
 		yTick.Position1 = fyne.NewPos(axisRange.yAxisOffset+axisRange.tickLength, tickHeight)
@@ -170,18 +289,40 @@ func (g *GraphStruct) addYTicks(size *fyne.Size, axisRange *axisRange) {
 	}
 }
 
-func (g *GraphStruct) addLines(size *fyne.Size, axisRange *axisRange, data []float32) {
+func (g *GraphStruct) addLines(size *fyne.Size, axisRange *axisRange, data []Series) {
 	g.lines = []*canvas.Line{}
-	for index := range data {
-		if index == 0 {
-			continue
+	for seriesIndex, series := range data {
+		for index := range series.Points {
+			if index == 0 {
+				continue
+			}
+			previous := series.Points[index-1]
+			point := series.Points[index]
+			if math.IsNaN(float64(previous.Y)) || math.IsNaN(float64(point.Y)) {
+				continue
+			}
+			line := &canvas.Line{}
+			line.Position1 = fyne.NewPos(xPosition(previous.X, size, axisRange), linearMap(previous.Y, axisRange.realizedMin, axisRange.realizedMax, size.Height, 0))
+			line.Position2 = fyne.NewPos(xPosition(point.X, size, axisRange), linearMap(point.Y, axisRange.realizedMin, axisRange.realizedMax, size.Height, 0))
+			line.StrokeColor = seriesColor(seriesIndex)
+			line.StrokeWidth = 1
+			g.lines = append(g.lines, line)
 		}
-		line := &canvas.Line{}
-		line.Position1 = fyne.NewPos(axisRange.yAxisOffset+linearMap(float32(index-1), 0, float32(len(data)), 0, size.Width), linearMap(data[index-1], axisRange.realizedMin, axisRange.realizedMax, size.Height, 0))
-		line.Position2 = fyne.NewPos(axisRange.yAxisOffset+linearMap(float32(index), 0, float32(len(data)), 0, size.Width), linearMap(data[index], axisRange.realizedMin, axisRange.realizedMax, size.Height, 0))
-		line.StrokeColor = primaryColor()
-		line.StrokeWidth = 1
-		g.lines = append(g.lines, line)
+	}
+}
+
+func (g *GraphStruct) addLegend(size *fyne.Size, data []Series) {
+	g.legend = []*canvas.Text{}
+	yPos := float32(0)
+	for seriesIndex, series := range data {
+		text := series.Name
+		if rate := sampleRate(series); rate > 0 {
+			text = fmt.Sprintf("%s (%.1f Hz)", series.Name, rate)
+		}
+		label := canvas.NewText(text, seriesColor(seriesIndex))
+		label.Move(fyne.NewPos(size.Width-label.MinSize().Width, yPos))
+		yPos += label.MinSize().Height
+		g.legend = append(g.legend, label)
 	}
 }
 
@@ -202,17 +343,23 @@ func (g *GraphStruct) addGraphObjects(graphContainer *fyne.Container) {
 	for _, line := range g.lines {
 		graphContainer.Objects = append(graphContainer.Objects, line)
 	}
+	for _, label := range g.legend {
+		graphContainer.Objects = append(graphContainer.Objects, label)
+	}
 	graphContainer.Objects = append(graphContainer.Objects, g.xAxis, g.yAxis)
 }
 
 func (g *GraphStruct) Show(graphContainer *fyne.Container) {
-	g.render(graphContainer, graphContainer.Size(), []float32{})
+	g.render(graphContainer, graphContainer.Size(), []Series{})
 }
 
-func (g *GraphStruct) Update(graphContainer *fyne.Container, data []float32) {
+func (g *GraphStruct) Update(graphContainer *fyne.Container, data []Series) {
 	g.render(graphContainer, graphContainer.Size(), data)
 }
 
+// linearMap works in float64 so ranges spanning most of float32 don't
+// overflow.
 func linearMap(value, inputMin, inputMax, outputMin, outputMax float32) float32 {
-	return outputMin + (outputMax-outputMin)/(inputMax-inputMin)*(value-inputMin)
+	scale := (float64(outputMax) - float64(outputMin)) / (float64(inputMax) - float64(inputMin))
+	return float32(float64(outputMin) + scale*(float64(value)-float64(inputMin)))
 }
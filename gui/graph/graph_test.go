@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"math"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+)
+
+func TestSeriesExtent(t *testing.T) {
+	nan := float32(math.NaN())
+	tests := []struct {
+		name         string
+		data         []Series
+		xMin, xMax   float32
+		yMin, yMax   float32
+		wantX, wantY bool
+	}{
+		{
+			name: "two series",
+			data: []Series{
+				{Name: "a", Points: []Point{{1, 5}, {2, -3}}},
+				{Name: "b", Points: []Point{{0, 2}, {4, 8}}},
+			},
+			xMin: 0, xMax: 4, yMin: -3, yMax: 8, wantX: true, wantY: true,
+		},
+		{
+			name: "gaps and an empty series",
+			data: []Series{
+				{Name: "a", Points: []Point{{1, nan}, {2, 3}, {3, nan}}},
+				{Name: "b"},
+			},
+			xMin: 1, xMax: 3, yMin: 3, yMax: 3, wantX: true, wantY: true,
+		},
+		{
+			name: "only gaps",
+			data: []Series{{Name: "a", Points: []Point{{1, nan}}}},
+			xMin: 1, xMax: 1, wantX: true,
+		},
+		{
+			name: "no data",
+			data: []Series{{Name: "a"}, {Name: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		xMin, xMax, okX := seriesSpan(tt.data)
+		if okX != tt.wantX || (okX && (xMin != tt.xMin || xMax != tt.xMax)) {
+			t.Errorf("%s: seriesSpan = %v, %v, %t, want %v, %v, %t", tt.name, xMin, xMax, okX, tt.xMin, tt.xMax, tt.wantX)
+		}
+		yMin, yMax, okY := seriesRange(tt.data)
+		if okY != tt.wantY || (okY && (yMin != tt.yMin || yMax != tt.yMax)) {
+			t.Errorf("%s: seriesRange = %v, %v, %t, want %v, %v, %t", tt.name, yMin, yMax, okY, tt.yMin, tt.yMax, tt.wantY)
+		}
+	}
+}
+
+func TestCreateAxisRangeExtremes(t *testing.T) {
+	test.NewTempApp(t)
+	tests := []struct {
+		name   string
+		points []Point
+	}{
+		{"both limits", []Point{{0, math.MaxFloat32}, {1, -math.MaxFloat32}}},
+		{"upper limit", []Point{{0, 1}, {1, math.MaxFloat32}, {2, 3}}},
+		{"lower limit", []Point{{0, -math.MaxFloat32}, {1, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := fyne.NewSize(400, 300)
+			data := []Series{{Name: "value", Points: tt.points}}
+			graph := &GraphStruct{}
+			axisRange := graph.createAxisRange(&size, data)
+			for name, value := range map[string]float32{
+				"realizedMin": axisRange.realizedMin,
+				"realizedMax": axisRange.realizedMax,
+				"tickMin":     axisRange.tickMin,
+				"tickMax":     axisRange.tickMax,
+				"zeroHeight":  axisRange.zeroHeight,
+			} {
+				if math.IsInf(float64(value), 0) || math.IsNaN(float64(value)) {
+					t.Errorf("%s = %v", name, value)
+				}
+			}
+			if axisRange.numTicks < 1 || axisRange.numTicks > 100 {
+				t.Errorf("numTicks = %d", axisRange.numTicks)
+			}
+			graph.Update(container.NewWithoutLayout(), data)
+		})
+	}
+}
@@ -1,22 +1,40 @@
 package gui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image/color"
-	"slices"
+	"io"
+	"math"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
+	"github.com/taylorcoons/serial-plotter/capture"
 	"github.com/taylorcoons/serial-plotter/datasources"
 	"github.com/taylorcoons/serial-plotter/datasources/dummy"
+	"github.com/taylorcoons/serial-plotter/datasources/firmata"
+	"github.com/taylorcoons/serial-plotter/datasources/jsonmap"
+	"github.com/taylorcoons/serial-plotter/datasources/modbus"
+	"github.com/taylorcoons/serial-plotter/datasources/mqtt"
+	"github.com/taylorcoons/serial-plotter/datasources/pipe"
+	"github.com/taylorcoons/serial-plotter/datasources/replay"
 	"github.com/taylorcoons/serial-plotter/datasources/serial"
+	"github.com/taylorcoons/serial-plotter/datasources/tcp"
+	"github.com/taylorcoons/serial-plotter/datasources/udp"
+	"github.com/taylorcoons/serial-plotter/datasources/web"
 	"github.com/taylorcoons/serial-plotter/gui/graph"
 	"github.com/taylorcoons/serial-plotter/gui/preference"
+	"github.com/taylorcoons/serial-plotter/recorder"
 	"github.com/taylorcoons/serial-plotter/transformers"
 	"github.com/taylorcoons/serial-plotter/transformers/gaussian"
 	"github.com/taylorcoons/serial-plotter/transformers/passthrough"
@@ -26,23 +44,38 @@ import (
 type appState struct {
 	dataSourceType string
 	serialSource   *serial.SerialPort
+	serialStatus   *widget.Label
+	autoReconnect  bool
 	dummySource    *dummy.Dummy
+	replaySource   *replay.Replay
+	tcpSource      *tcp.TCP
+	udpSource      *udp.UDP
+	pipeSource     *pipe.Pipe
+	mqttSource     *mqtt.MQTT
+	webSource      *web.Web
+	modbusSource   *modbus.Modbus
+	firmataSource  *firmata.Firmata
+	capture        *capture.Writer
+	monitor        *monitor
 	transform      transformers.Transformer
 	window         fyne.Window
-	data           []float32
+	data           map[string][]datasources.Sample
+	channels       []string
+	start          *datasources.Sample
+	clock          *datasources.DeviceClock
+	recording      atomic.Pointer[recorder.CSVRecorder]
 	app            fyne.App
 }
 
-func (a *appState) DataSourcesPanel(serialSourceContainer *fyne.Container, dummySourceContainer *fyne.Container) *fyne.Container {
-	dataSourcesList := []string{"Serial", "Dummy"}
+func (a *appState) DataSourcesPanel(sourceOptions map[string]*fyne.Container) *fyne.Container {
+	dataSourcesList := []string{"Serial", "TCP", "UDP", "Pipe", "MQTT", "Web", "Modbus", "Firmata", "Dummy", "Replay"}
 	dataSourcesSelect := widget.NewSelect(dataSourcesList, func(value string) {
-		serialSourceContainer.Hide()
-		dummySourceContainer.Hide()
-		switch value {
-		case "Serial":
-			serialSourceContainer.Show()
-		case "Dummy":
-			dummySourceContainer.Show()
+		for name, options := range sourceOptions {
+			if name == value {
+				options.Show()
+			} else {
+				options.Hide()
+			}
 		}
 		a.app.Preferences().SetString(preference.DataSource.String(), value)
 		a.dataSourceType = value
@@ -55,43 +88,684 @@ func (a *appState) DataSourcesPanel(serialSourceContainer *fyne.Container, dummy
 }
 
 func (a *appState) SerialSourceOptions() (*fyne.Container, error) {
-	ports, err := serial.GetPorts()
+	baudOptions := append(serial.BaudOptions(), serial.AutoBaud)
+	defaultBaud := a.app.Preferences().StringWithFallback(preference.Baud.String(), "9600")
+	a.serialSource = serial.New("", 9600)
+	lineOptions, loadLineOptions := a.LineControlOptions()
+	loadLineOptions("")
+	portSelect := a.PortSelect(func(portName string) {
+		a.serialSource.SetPortName(portName)
+		a.app.Preferences().SetString(preference.PortName.String(), portName)
+		loadLineOptions(portName)
+	})
+	a.serialStatus = widget.NewLabel("")
+	a.serialStatus.Hide()
+	validateBaud := func(value string) error {
+		if value == serial.AutoBaud {
+			return nil
+		}
+		_, err := serial.ParseBaud(value)
+		return err
+	}
+	baudSelect := widget.NewSelectEntry(baudOptions)
+	baudSelect.PlaceHolder = "Baud"
+	baudSelect.Validator = validateBaud
+	baudSelect.OnChanged = func(value string) {
+		if value == serial.AutoBaud {
+			a.serialSource.SetAutoBaud()
+		} else {
+			baudValue, err := serial.ParseBaud(value)
+			if err != nil {
+				fmt.Println("failed to parse baud option", err)
+				return
+			}
+			a.serialSource.SetBaud(baudValue)
+		}
+		a.serialStatus.Hide()
+		a.app.Preferences().SetString(preference.Baud.String(), value)
+	}
+	baudSelect.SetText(defaultBaud)
+	terminatorSelect := widget.NewSelectEntry(serial.TerminatorOptions())
+	terminatorSelect.OnChanged = func(value string) {
+		terminator, err := serial.ParseTerminator(value)
+		if err != nil {
+			fmt.Println("failed to parse terminator option", err)
+			return
+		}
+		a.serialSource.SetTerminator(terminator)
+		a.app.Preferences().SetString(preference.Terminator.String(), value)
+	}
+	terminatorSelect.SetText(a.app.Preferences().StringWithFallback(preference.Terminator.String(), `\n`))
+	terminatorSelect.PlaceHolder = "Line Terminator"
+	grammarSelect := widget.NewSelect(serial.GrammarOptions(), func(value string) {
+		grammar, err := serial.ParseGrammar(value)
+		if err != nil {
+			fmt.Println("failed to parse grammar option", err)
+			return
+		}
+		a.serialSource.SetGrammar(grammar)
+		a.app.Preferences().SetString(preference.Grammar.String(), value)
+	})
+	grammarSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.Grammar.String(), serial.ArduinoGrammar.String()))
+	nonFiniteSelect := widget.NewSelect(serial.NonFiniteOptions(), func(value string) {
+		nonFinite, err := serial.ParseNonFinitePolicy(value)
+		if err != nil {
+			fmt.Println("failed to parse non-finite option", err)
+			return
+		}
+		a.serialSource.SetNonFinitePolicy(nonFinite)
+		a.app.Preferences().SetString(preference.NonFinite.String(), value)
+	})
+	nonFiniteSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.NonFinite.String(), serial.DropNonFinite.String()))
+	timestampField := widget.NewEntry()
+	timestampField.PlaceHolder = "Timestamp Field"
+	timestampUnitSelect := widget.NewSelect(serial.TimestampUnitOptions(), nil)
+	setTimestampField := func() {
+		unit, err := serial.ParseTimestampUnit(timestampUnitSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse timestamp unit option", err)
+			return
+		}
+		a.serialSource.SetTimestampField(timestampField.Text, unit)
+		a.app.Preferences().SetString(preference.TimestampField.String(), timestampField.Text)
+		a.app.Preferences().SetString(preference.TimestampUnit.String(), timestampUnitSelect.Selected)
+	}
+	timestampField.SetText(a.app.Preferences().StringWithFallback(preference.TimestampField.String(), ""))
+	timestampUnitSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.TimestampUnit.String(), "ms"))
+	setTimestampField()
+	timestampField.OnChanged = func(string) { setTimestampField() }
+	timestampUnitSelect.OnChanged = func(string) { setTimestampField() }
+	timestampOptions := container.NewBorder(nil, nil, nil, timestampUnitSelect, timestampField)
+	serialOptions := container.NewVBox(portSelect, baudSelect, a.serialStatus, a.SerialConfigOptions(), lineOptions, container.NewGridWithColumns(2, terminatorSelect, grammarSelect), a.FramingOptions(), nonFiniteSelect, timestampOptions, a.ReconnectToggle(), a.CaptureToggle())
+	return serialOptions, nil
+}
+
+// FramingOptions selects between text lines and binary frames holding
+// records of a layout, and shows how many frames have been dropped.
+func (a *appState) FramingOptions() *fyne.Container {
+	layoutEntry := widget.NewEntry()
+	layoutEntry.PlaceHolder = "Layout, e.g. u32 t; i16 ax, ay, az; f32 temp"
+	byteOrderSelect := widget.NewSelect(serial.ByteOrderOptions(), nil)
+	framingSelect := widget.NewSelect(serial.FramingOptions(), nil)
+	setFraming := func() {
+		framing, err := serial.ParseFraming(framingSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse framing option", err)
+			return
+		}
+		a.app.Preferences().SetString(preference.Framing.String(), framingSelect.Selected)
+		a.app.Preferences().SetString(preference.Layout.String(), layoutEntry.Text)
+		a.app.Preferences().SetString(preference.ByteOrder.String(), byteOrderSelect.Selected)
+		if framing == serial.LineFraming {
+			layoutEntry.Hide()
+			byteOrderSelect.Hide()
+			a.serialSource.SetFraming(framing, serial.Layout{})
+			return
+		}
+		layoutEntry.Show()
+		byteOrderSelect.Show()
+		byteOrder, err := serial.ParseByteOrder(byteOrderSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse byte order option", err)
+			return
+		}
+		layout, err := serial.ParseLayout(layoutEntry.Text, byteOrder)
+		if err != nil {
+			return
+		}
+		a.serialSource.SetFraming(framing, layout)
+	}
+	layoutEntry.Validator = func(value string) error {
+		_, err := serial.ParseLayout(value, serial.LittleEndian)
+		return err
+	}
+	layoutEntry.SetText(a.app.Preferences().StringWithFallback(preference.Layout.String(), ""))
+	byteOrderSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.ByteOrder.String(), serial.LittleEndian.String()))
+	framingSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.Framing.String(), serial.LineFraming.String()))
+	setFraming()
+	layoutEntry.OnChanged = func(string) { setFraming() }
+	byteOrderSelect.OnChanged = func(string) { setFraming() }
+	framingSelect.OnChanged = func(string) { setFraming() }
+
+	framingErrors := widget.NewLabel("")
+	framingErrors.Importance = widget.DangerImportance
+	framingErrors.Hide()
+	go func() {
+		for range time.Tick(time.Second) {
+			count := a.serialSource.FramingErrors()
+			fyne.Do(func() {
+				if count == 0 {
+					framingErrors.Hide()
+					return
+				}
+				framingErrors.SetText(fmt.Sprintf("%d framing errors", count))
+				framingErrors.Show()
+			})
+		}
+	}()
+	return container.NewVBox(container.NewBorder(nil, nil, nil, byteOrderSelect, framingSelect), layoutEntry, framingErrors)
+}
+
+// PortSelect lists the serial ports labelled with their USB details and
+// keeps the list up to date as boards are plugged in and unplugged. The
+// last used port is selected as soon as it appears.
+func (a *appState) PortSelect(onSelected func(portName string)) *widget.Select {
+	portNames := map[string]string{}
+	selectedPort := ""
+	portSelect := widget.NewSelect([]string{}, func(label string) {
+		portName, ok := portNames[label]
+		if !ok {
+			return
+		}
+		selectedPort = portName
+		onSelected(portName)
+	})
+	portSelect.PlaceHolder = "Serial Port"
+	setPorts := func(ports []serial.PortInfo) {
+		preferredPort := a.app.Preferences().StringWithFallback(preference.PortName.String(), "")
+		portNames = map[string]string{}
+		labels := []string{}
+		selectedLabel := ""
+		preferredLabel := ""
+		for _, port := range ports {
+			label := port.String()
+			portNames[label] = port.Name
+			labels = append(labels, label)
+			if port.Name == selectedPort {
+				selectedLabel = label
+			}
+			if port.Name == preferredPort {
+				preferredLabel = label
+			}
+		}
+		portSelect.Options = labels
+		switch {
+		case selectedLabel != "":
+			portSelect.Selected = selectedLabel
+		case selectedPort != "":
+			// Keep the unplugged port selected so it can be reconnected
+			portSelect.Selected = selectedPort + " (unplugged)"
+		case preferredLabel != "":
+			portSelect.SetSelected(preferredLabel)
+		}
+		portSelect.Refresh()
+	}
+	ports, err := serial.GetPortDetails()
 	if err != nil {
 		fmt.Println("failed to get ports", err)
 	}
-	defaultPort := a.app.Preferences().StringWithFallback(preference.PortName.String(), "")
-	if !slices.Contains(ports, defaultPort) {
-		defaultPort = ""
+	setPorts(ports)
+	go serial.WatchPorts(context.Background(), serial.DefaultPortPollInterval, func(ports []serial.PortInfo) {
+		fyne.Do(func() {
+			setPorts(ports)
+		})
+	})
+	return portSelect
+}
+
+// ReconnectToggle keeps plotting through a disconnect by reopening the
+// serial port when the device comes back.
+func (a *appState) ReconnectToggle() *widget.Check {
+	reconnectCheck := widget.NewCheck("Auto Reconnect", func(checked bool) {
+		a.autoReconnect = checked
+		a.app.Preferences().SetBool(preference.AutoReconnect.String(), checked)
+	})
+	reconnectCheck.SetChecked(a.app.Preferences().BoolWithFallback(preference.AutoReconnect.String(), false))
+	return reconnectCheck
+}
+
+// SerialConfigOptions selects the data bits, parity, stop bits and
+// emulated flow control of the serial port. The settings are validated when
+// the port is opened.
+func (a *appState) SerialConfigOptions() *fyne.Container {
+	dataBitsSelect := widget.NewSelect(serial.DataBitsOptions(), nil)
+	paritySelect := widget.NewSelect(serial.ParityOptions(), nil)
+	stopBitsSelect := widget.NewSelect(serial.StopBitsOptions(), nil)
+	flowControlSelect := widget.NewSelect(serial.FlowControlOptions(), nil)
+	setConfig := func(string) {
+		config := a.serialSource.Config()
+		var err error
+		config.DataBits, err = serial.ParseDataBits(dataBitsSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse data bits option", err)
+			return
+		}
+		config.Parity, err = serial.ParseParity(paritySelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse parity option", err)
+			return
+		}
+		config.StopBits, err = serial.ParseStopBits(stopBitsSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse stop bits option", err)
+			return
+		}
+		config.FlowControl, err = serial.ParseFlowControl(flowControlSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse flow control option", err)
+			return
+		}
+		a.serialSource.SetConfig(config)
+		a.app.Preferences().SetString(preference.DataBits.String(), dataBitsSelect.Selected)
+		a.app.Preferences().SetString(preference.Parity.String(), paritySelect.Selected)
+		a.app.Preferences().SetString(preference.StopBits.String(), stopBitsSelect.Selected)
+		a.app.Preferences().SetString(preference.FlowControl.String(), flowControlSelect.Selected)
+	}
+	dataBitsSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.DataBits.String(), "8"))
+	paritySelect.SetSelected(a.app.Preferences().StringWithFallback(preference.Parity.String(), "None"))
+	stopBitsSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.StopBits.String(), "1"))
+	// Saved names may predate flow control being labelled as emulated
+	flowControl, err := serial.ParseFlowControl(a.app.Preferences().StringWithFallback(preference.FlowControl.String(), serial.NoFlowControl.String()))
+	if err != nil {
+		fmt.Println("failed to parse flow control option", err)
 	}
-	baudOptions := serial.BaudOptions()
-	defaultBaud := a.app.Preferences().StringWithFallback(preference.Baud.String(), "9600")
-	defaultBaudValue, err := strconv.Atoi(defaultBaud)
+	flowControlSelect.SetSelected(flowControl.String())
+	setConfig("")
+	dataBitsSelect.OnChanged = setConfig
+	paritySelect.OnChanged = setConfig
+	stopBitsSelect.OnChanged = setConfig
+	flowControlSelect.OnChanged = setConfig
+	return container.NewGridWithColumns(4, dataBitsSelect, paritySelect, stopBitsSelect, flowControlSelect)
+}
+
+// LineControlOptions selects what is done to DTR and RTS when the port opens,
+// remembered for each port, and how long to discard data after a reset. The
+// returned function loads the line actions saved for a port.
+func (a *appState) LineControlOptions() (*fyne.Container, func(portName string)) {
+	portName := ""
+	dtrSelect := widget.NewSelect(serial.LineActionOptions(), nil)
+	rtsSelect := widget.NewSelect(serial.LineActionOptions(), nil)
+	setLineActions := func(string) {
+		dtr, err := serial.ParseLineAction(dtrSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse DTR option", err)
+			return
+		}
+		rts, err := serial.ParseLineAction(rtsSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse RTS option", err)
+			return
+		}
+		a.serialSource.SetLineActions(dtr, rts)
+		a.app.Preferences().SetString(preference.DTR.For(portName), dtrSelect.Selected)
+		a.app.Preferences().SetString(preference.RTS.For(portName), rtsSelect.Selected)
+	}
+	loadLineActions := func(value string) {
+		portName = value
+		dtrSelect.OnChanged = nil
+		rtsSelect.OnChanged = nil
+		dtrSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.DTR.For(portName), serial.LineAssert.String()))
+		rtsSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.RTS.For(portName), serial.LineAssert.String()))
+		setLineActions("")
+		dtrSelect.OnChanged = setLineActions
+		rtsSelect.OnChanged = setLineActions
+	}
+
+	discardMs := widget.NewEntry()
+	discardMs.PlaceHolder = "Discard ms"
+	discardLines := widget.NewEntry()
+	discardLines.PlaceHolder = "Discard lines"
+	validateCount := func(value string) error {
+		if value == "" {
+			return nil
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return fmt.Errorf("must be a positive whole number")
+		}
+		return nil
+	}
+	discardMs.Validator = validateCount
+	discardLines.Validator = validateCount
+	setResetDiscard := func(string) {
+		if discardMs.Validate() != nil || discardLines.Validate() != nil {
+			return
+		}
+		ms, _ := strconv.Atoi(discardMs.Text)
+		lines, _ := strconv.Atoi(discardLines.Text)
+		a.serialSource.SetResetDiscard(time.Duration(ms)*time.Millisecond, lines)
+		a.app.Preferences().SetString(preference.DiscardMs.String(), discardMs.Text)
+		a.app.Preferences().SetString(preference.DiscardLines.String(), discardLines.Text)
+	}
+	discardMs.SetText(a.app.Preferences().StringWithFallback(preference.DiscardMs.String(), ""))
+	discardLines.SetText(a.app.Preferences().StringWithFallback(preference.DiscardLines.String(), ""))
+	setResetDiscard("")
+	discardMs.OnChanged = setResetDiscard
+	discardLines.OnChanged = setResetDiscard
+
+	lineOptions := container.NewGridWithColumns(2,
+		container.NewBorder(nil, nil, widget.NewLabel("DTR"), nil, dtrSelect),
+		container.NewBorder(nil, nil, widget.NewLabel("RTS"), nil, rtsSelect),
+		discardMs,
+		discardLines,
+	)
+	return lineOptions, loadLineActions
+}
+
+// TCPSourceOptions connects to a device streaming plotter lines over TCP,
+// or listens for one to connect. Lines are parsed with the serial settings.
+func (a *appState) TCPSourceOptions() *fyne.Container {
+	selectedMode := a.app.Preferences().StringWithFallback(preference.TCPMode.String(), tcp.Client.String())
+	mode, err := tcp.ParseMode(selectedMode)
 	if err != nil {
-		fmt.Println("failed to parse baud option", err)
-		return nil, err
+		fmt.Println("failed to parse tcp mode option", err)
+		selectedMode = tcp.Client.String()
+	}
+	address := a.app.Preferences().StringWithFallback(preference.TCPAddress.String(), "")
+	a.tcpSource = tcp.New(mode, address)
+	addressEntry := widget.NewEntry()
+	addressEntry.PlaceHolder = "host:port"
+	addressEntry.SetText(address)
+	addressEntry.OnChanged = func(value string) {
+		a.tcpSource.SetAddress(value)
+		a.app.Preferences().SetString(preference.TCPAddress.String(), value)
+	}
+	modeSelect := widget.NewSelect(tcp.ModeOptions(), func(value string) {
+		mode, err := tcp.ParseMode(value)
+		if err != nil {
+			fmt.Println("failed to parse tcp mode option", err)
+			return
+		}
+		if mode == tcp.Listen {
+			addressEntry.PlaceHolder = ":port"
+		} else {
+			addressEntry.PlaceHolder = "host:port"
+		}
+		addressEntry.Refresh()
+		a.tcpSource.SetMode(mode)
+		a.app.Preferences().SetString(preference.TCPMode.String(), value)
+	})
+	modeSelect.SetSelected(selectedMode)
+	return container.NewVBox(modeSelect, addressEntry)
+}
+
+// UDPSourceOptions binds to a UDP port, or joins a multicast group, to plot
+// datagrams from boards on a wireless rig. Lines are parsed with the serial
+// settings.
+func (a *appState) UDPSourceOptions() *fyne.Container {
+	address := a.app.Preferences().StringWithFallback(preference.UDPAddress.String(), "")
+	prefixSenders := a.app.Preferences().BoolWithFallback(preference.UDPPrefixSenders.String(), false)
+	a.udpSource = udp.New(address, prefixSenders)
+	addressEntry := widget.NewEntry()
+	addressEntry.PlaceHolder = ":port or group:port"
+	addressEntry.SetText(address)
+	addressEntry.OnChanged = func(value string) {
+		a.udpSource.SetAddress(value)
+		a.app.Preferences().SetString(preference.UDPAddress.String(), value)
+	}
+	prefixCheck := widget.NewCheck("Prefix Sender", func(checked bool) {
+		a.udpSource.SetPrefixSenders(checked)
+		a.app.Preferences().SetBool(preference.UDPPrefixSenders.String(), checked)
+	})
+	prefixCheck.SetChecked(prefixSenders)
+	return container.NewVBox(addressEntry, prefixCheck)
+}
+
+// PipeSourceOptions plots lines read from stdin, a named pipe or the output
+// of a command. Lines are parsed with the serial settings.
+func (a *appState) PipeSourceOptions() *fyne.Container {
+	selectedKind := a.app.Preferences().StringWithFallback(preference.PipeKind.String(), pipe.Command.String())
+	kind, err := pipe.ParseKind(selectedKind)
+	if err != nil {
+		fmt.Println("failed to parse pipe kind option", err)
+		selectedKind = pipe.Command.String()
+		kind = pipe.Command
 	}
-	a.serialSource = serial.New(defaultPort, defaultBaudValue)
-	portSelect := widget.NewSelect(ports, func(value string) {
-		a.serialSource.SetPortName(value)
-		a.app.Preferences().SetString(preference.PortName.String(), value)
+	target := a.app.Preferences().StringWithFallback(preference.PipeTarget.String(), "")
+	a.pipeSource = pipe.New(kind, target)
+	targetEntry := widget.NewEntry()
+	targetEntry.SetText(target)
+	targetEntry.OnChanged = func(value string) {
+		a.pipeSource.SetTarget(value)
+		a.app.Preferences().SetString(preference.PipeTarget.String(), value)
+	}
+	kindSelect := widget.NewSelect(pipe.KindOptions(), func(value string) {
+		kind, err := pipe.ParseKind(value)
+		if err != nil {
+			fmt.Println("failed to parse pipe kind option", err)
+			return
+		}
+		switch kind {
+		case pipe.Stdin:
+			targetEntry.Disable()
+		case pipe.NamedPipe:
+			targetEntry.PlaceHolder = "Named pipe path"
+			targetEntry.Enable()
+		case pipe.Command:
+			targetEntry.PlaceHolder = "Command, e.g. python sim.py"
+			targetEntry.Enable()
+		}
+		targetEntry.Refresh()
+		a.pipeSource.SetKind(kind)
+		a.app.Preferences().SetString(preference.PipeKind.String(), value)
 	})
-	if defaultPort != "" {
-		portSelect.SetSelected(defaultPort)
-	} else {
-		portSelect.PlaceHolder = "Serial Port"
+	kindSelect.SetSelected(selectedKind)
+	return container.NewVBox(kindSelect, targetEntry)
+}
+
+// MQTTSourceOptions subscribes to topics on an MQTT broker. The password is
+// not saved with the other settings.
+func (a *appState) MQTTSourceOptions() *fyne.Container {
+	broker := a.app.Preferences().StringWithFallback(preference.MQTTBroker.String(), "tcp://localhost:1883")
+	topics := a.app.Preferences().StringWithFallback(preference.MQTTTopics.String(), "")
+	selectedQoS := a.app.Preferences().StringWithFallback(preference.MQTTQoS.String(), "0")
+	qos, err := mqtt.ParseQoS(selectedQoS)
+	if err != nil {
+		fmt.Println("failed to parse mqtt qos option", err)
+		selectedQoS = "0"
+	}
+	a.mqttSource = mqtt.New(broker, mqtt.ParseTopics(topics), qos)
+	brokerEntry := widget.NewEntry()
+	brokerEntry.PlaceHolder = "tcp://host:1883"
+	brokerEntry.SetText(broker)
+	brokerEntry.OnChanged = func(value string) {
+		a.mqttSource.SetBroker(value)
+		a.app.Preferences().SetString(preference.MQTTBroker.String(), value)
+	}
+	topicsEntry := widget.NewEntry()
+	topicsEntry.PlaceHolder = "Topics, e.g. sensors/#, rig/+/temp"
+	topicsEntry.SetText(topics)
+	topicsEntry.OnChanged = func(value string) {
+		a.mqttSource.SetTopics(mqtt.ParseTopics(value))
+		a.app.Preferences().SetString(preference.MQTTTopics.String(), value)
 	}
-	baudSelect := widget.NewSelect(baudOptions, func(value string) {
-		baudValue, err := strconv.Atoi(value)
+	qosSelect := widget.NewSelect(mqtt.QoSOptions(), func(value string) {
+		qos, err := mqtt.ParseQoS(value)
 		if err != nil {
-			fmt.Println("failed to parse baud option", err)
+			fmt.Println("failed to parse mqtt qos option", err)
 			return
 		}
-		a.serialSource.SetBaud(baudValue)
-		a.app.Preferences().SetString(preference.Baud.String(), value)
+		a.mqttSource.SetQoS(qos)
+		a.app.Preferences().SetString(preference.MQTTQoS.String(), value)
 	})
-	baudSelect.SetSelected(defaultBaud)
-	serialOptions := container.NewVBox(portSelect, baudSelect)
-	return serialOptions, nil
+	qosSelect.SetSelected(selectedQoS)
+	usernameEntry := widget.NewEntry()
+	usernameEntry.PlaceHolder = "Username"
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.PlaceHolder = "Password"
+	setCredentials := func(string) {
+		a.mqttSource.SetCredentials(usernameEntry.Text, passwordEntry.Text)
+		a.app.Preferences().SetString(preference.MQTTUsername.String(), usernameEntry.Text)
+	}
+	usernameEntry.SetText(a.app.Preferences().StringWithFallback(preference.MQTTUsername.String(), ""))
+	setCredentials("")
+	usernameEntry.OnChanged = setCredentials
+	passwordEntry.OnChanged = setCredentials
+	qosOptions := container.NewBorder(nil, nil, widget.NewLabel("QoS"), nil, qosSelect)
+	return container.NewVBox(brokerEntry, topicsEntry, qosOptions, usernameEntry, passwordEntry)
+}
+
+// WebSourceOptions reads JSON samples from a WebSocket or Server-Sent
+// Events URL, optionally mapping JSON paths to channels.
+func (a *appState) WebSourceOptions() *fyne.Container {
+	url := a.app.Preferences().StringWithFallback(preference.WebURL.String(), "")
+	a.webSource = web.New(url)
+	urlEntry := widget.NewEntry()
+	urlEntry.PlaceHolder = "ws://host/samples or http://host/events"
+	urlEntry.SetText(url)
+	urlEntry.OnChanged = func(value string) {
+		a.webSource.SetURL(value)
+		a.app.Preferences().SetString(preference.WebURL.String(), value)
+	}
+	mappingEntry := widget.NewEntry()
+	mappingEntry.PlaceHolder = "Mapping, e.g. temp=$.env.temp, rh=$.rh"
+	mappingEntry.Validator = func(value string) error {
+		_, err := jsonmap.ParseMapping(value)
+		return err
+	}
+	mappingEntry.OnChanged = func(value string) {
+		mapping, err := jsonmap.ParseMapping(value)
+		if err != nil {
+			fmt.Println("failed to parse json mapping", err)
+			return
+		}
+		a.webSource.SetMapping(mapping)
+		a.app.Preferences().SetString(preference.WebMapping.String(), value)
+	}
+	mappingEntry.SetText(a.app.Preferences().StringWithFallback(preference.WebMapping.String(), ""))
+	timeField := widget.NewEntry()
+	timeField.PlaceHolder = "Timestamp Path"
+	timeUnitSelect := widget.NewSelect(serial.TimestampUnitOptions(), nil)
+	setTimeField := func() {
+		unit, err := serial.ParseTimestampUnit(timeUnitSelect.Selected)
+		if err != nil {
+			fmt.Println("failed to parse timestamp unit option", err)
+			return
+		}
+		var path jsonmap.Path
+		if timeField.Text != "" {
+			path, err = jsonmap.ParsePath(timeField.Text)
+			if err != nil {
+				fmt.Println("failed to parse timestamp path", err)
+				return
+			}
+		}
+		a.webSource.SetTimeField(path, unit)
+		a.app.Preferences().SetString(preference.WebTimeField.String(), timeField.Text)
+		a.app.Preferences().SetString(preference.WebTimeUnit.String(), timeUnitSelect.Selected)
+	}
+	timeField.SetText(a.app.Preferences().StringWithFallback(preference.WebTimeField.String(), ""))
+	timeUnitSelect.SetSelected(a.app.Preferences().StringWithFallback(preference.WebTimeUnit.String(), "ms"))
+	setTimeField()
+	timeField.OnChanged = func(string) { setTimeField() }
+	timeUnitSelect.OnChanged = func(string) { setTimeField() }
+	timeOptions := container.NewBorder(nil, nil, nil, timeUnitSelect, timeField)
+	return container.NewVBox(urlEntry, mappingEntry, timeOptions)
+}
+
+// ModbusSourceOptions polls registers from Modbus instruments, over the
+// serial port configured in the serial options with RTU, or over TCP. Each
+// register is written on its own line as key=value fields.
+func (a *appState) ModbusSourceOptions() *fyne.Container {
+	selectedTransport := a.app.Preferences().StringWithFallback(preference.ModbusTransport.String(), modbus.RTU.String())
+	transport, err := modbus.ParseTransport(selectedTransport)
+	if err != nil {
+		fmt.Println("failed to parse modbus transport option", err)
+		selectedTransport = modbus.RTU.String()
+	}
+	address := a.app.Preferences().StringWithFallback(preference.ModbusAddress.String(), "")
+	a.modbusSource = modbus.New(transport, a.serialSource, address)
+	addressEntry := widget.NewEntry()
+	addressEntry.PlaceHolder = "host:502"
+	addressEntry.SetText(address)
+	addressEntry.OnChanged = func(value string) {
+		a.modbusSource.SetAddress(value)
+		a.app.Preferences().SetString(preference.ModbusAddress.String(), value)
+	}
+	transportSelect := widget.NewSelect(modbus.TransportOptions(), func(value string) {
+		transport, err := modbus.ParseTransport(value)
+		if err != nil {
+			fmt.Println("failed to parse modbus transport option", err)
+			return
+		}
+		if transport == modbus.TCP {
+			addressEntry.Enable()
+		} else {
+			addressEntry.Disable()
+		}
+		a.modbusSource.SetTransport(transport)
+		a.app.Preferences().SetString(preference.ModbusTransport.String(), value)
+	})
+	transportSelect.SetSelected(selectedTransport)
+	registersEntry := widget.NewMultiLineEntry()
+	registersEntry.PlaceHolder = "name=temp slave=1 function=input address=100 type=float32 order=CDAB scale=0.1"
+	registersEntry.SetMinRowsVisible(3)
+	registersEntry.Validator = func(value string) error {
+		_, err := modbus.ParseRegisters(value)
+		return err
+	}
+	registersEntry.OnChanged = func(value string) {
+		registers, err := modbus.ParseRegisters(value)
+		if err != nil {
+			fmt.Println("failed to parse modbus registers", err)
+			return
+		}
+		a.modbusSource.SetRegisters(registers)
+		a.app.Preferences().SetString(preference.ModbusRegisters.String(), value)
+	}
+	registersEntry.SetText(a.app.Preferences().StringWithFallback(preference.ModbusRegisters.String(), ""))
+	intervalEntry := widget.NewEntry()
+	intervalEntry.PlaceHolder = "Poll ms"
+	intervalEntry.Validator = func(value string) error {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return fmt.Errorf("poll interval must be a positive number of milliseconds")
+		}
+		return nil
+	}
+	intervalEntry.OnChanged = func(value string) {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return
+		}
+		a.modbusSource.SetInterval(time.Duration(ms) * time.Millisecond)
+		a.app.Preferences().SetString(preference.ModbusInterval.String(), value)
+	}
+	intervalEntry.SetText(a.app.Preferences().StringWithFallback(preference.ModbusInterval.String(), strconv.Itoa(int(modbus.DefaultPollInterval.Milliseconds()))))
+	intervalOptions := container.NewBorder(nil, nil, widget.NewLabel("Poll ms"), nil, intervalEntry)
+	return container.NewVBox(transportSelect, addressEntry, registersEntry, intervalOptions)
+}
+
+// FirmataSourceOptions plots the pins of a board running StandardFirmata
+// on the serial port configured in the serial options, StandardFirmata
+// talks at 57600 baud.
+func (a *appState) FirmataSourceOptions() *fyne.Container {
+	a.firmataSource = firmata.New(a.serialSource, firmata.Pins{})
+	pinsEntry := widget.NewEntry()
+	pinsEntry.PlaceHolder = "Pins, e.g. A0, A1, D2"
+	pinsEntry.Validator = func(value string) error {
+		_, err := firmata.ParsePins(value)
+		return err
+	}
+	pinsEntry.OnChanged = func(value string) {
+		pins, err := firmata.ParsePins(value)
+		if err != nil {
+			return
+		}
+		a.firmataSource.SetPins(pins)
+		a.app.Preferences().SetString(preference.FirmataPins.String(), value)
+	}
+	pinsEntry.SetText(a.app.Preferences().StringWithFallback(preference.FirmataPins.String(), "A0"))
+	intervalEntry := widget.NewEntry()
+	intervalEntry.PlaceHolder = "Sampling ms"
+	intervalEntry.Validator = func(value string) error {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 || ms > 0x3fff {
+			return fmt.Errorf("sampling interval must be 1 to 16383 milliseconds")
+		}
+		return nil
+	}
+	intervalEntry.OnChanged = func(value string) {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 || ms > 0x3fff {
+			return
+		}
+		a.firmataSource.SetInterval(time.Duration(ms) * time.Millisecond)
+		a.app.Preferences().SetString(preference.FirmataInterval.String(), value)
+	}
+	intervalEntry.SetText(a.app.Preferences().StringWithFallback(preference.FirmataInterval.String(), strconv.Itoa(int(firmata.DefaultSamplingInterval.Milliseconds()))))
+	intervalOptions := container.NewBorder(nil, nil, widget.NewLabel("Sampling ms"), nil, intervalEntry)
+	return container.NewVBox(pinsEntry, intervalOptions)
 }
 
 func (a *appState) DummySourceOptions() *fyne.Container {
@@ -117,6 +791,96 @@ func (a *appState) DummySourceOptions() *fyne.Container {
 	return container.NewVBox(functionSelect)
 }
 
+func (a *appState) ReplaySourceOptions(clearChannel chan int) *fyne.Container {
+	path := a.app.Preferences().StringWithFallback(preference.ReplayFile.String(), "")
+	selectedSpeed := a.app.Preferences().StringWithFallback(preference.ReplaySpeed.String(), "1x")
+	speed, err := replay.ParseSpeed(selectedSpeed)
+	if err != nil {
+		fmt.Println("failed to parse replay speed option", err)
+		selectedSpeed = "1x"
+		speed = 1
+	}
+	a.replaySource = replay.New(path, speed)
+	// Files are read through storage so non-file URIs work, the replay
+	// reopens the file on every start and looks for rotated files beside it
+	a.replaySource.SetOpen(func(path string) (io.ReadCloser, error) {
+		uri, err := replayURI(path)
+		if err != nil {
+			return nil, err
+		}
+		return storage.Reader(uri)
+	})
+	fileLabel := widget.NewLabel("No recording")
+	if uri, err := replayURI(path); path != "" && err == nil {
+		fileLabel.SetText(uri.Name())
+	}
+	fileButton := widget.NewButton("Open Recording", func() {
+		fileOpen := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				ErrorModal(fmt.Sprintf("Error choosing recording %s", err), a.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+			path := reader.URI().String()
+			a.replaySource.SetPath(path)
+			fileLabel.SetText(reader.URI().Name())
+			a.app.Preferences().SetString(preference.ReplayFile.String(), path)
+		}, a.window)
+		fileOpen.Show()
+	})
+	speedSelect := widget.NewSelect(replay.SpeedOptions(), func(value string) {
+		speed, err := replay.ParseSpeed(value)
+		if err != nil {
+			fmt.Println("failed to parse replay speed option", err)
+			return
+		}
+		a.replaySource.SetSpeed(speed)
+		a.app.Preferences().SetString(preference.ReplaySpeed.String(), value)
+	})
+	speedSelect.SetSelected(selectedSpeed)
+	var pauseButton *widget.Button
+	pauseButton = widget.NewButton("Pause", func() {
+		if a.replaySource.Paused() {
+			a.replaySource.Resume()
+			pauseButton.SetText("Pause")
+		} else {
+			a.replaySource.Pause()
+			pauseButton.SetText("Resume")
+		}
+	})
+	seekSlider := widget.NewSlider(0, 1)
+	seekSlider.Step = 0.01
+	seekSlider.OnChangeEnded = func(value float64) {
+		// Clear so the time axis does not run backwards
+		clearChannel <- 0
+		a.replaySource.Seek(time.Duration(value * float64(time.Second)))
+	}
+	go func() {
+		for range time.Tick(250 * time.Millisecond) {
+			fyne.Do(func() {
+				if a.dataSourceType != "Replay" {
+					return
+				}
+				seekSlider.Max = max(a.replaySource.Duration().Seconds(), 1)
+				seekSlider.SetValue(a.replaySource.Position().Seconds())
+			})
+		}
+	}()
+	return container.NewVBox(container.NewBorder(nil, nil, nil, fileButton, fileLabel), speedSelect, pauseButton, seekSlider)
+}
+
+// replayURI parses a saved replay file, which is a URI or, when saved by
+// older versions, a file path.
+func replayURI(path string) (fyne.URI, error) {
+	if !strings.Contains(path, "://") {
+		return storage.NewFileURI(path), nil
+	}
+	return storage.ParseURI(path)
+}
+
 func (a *appState) TransformOptions() *fyne.Container {
 	transformMap := map[string]transformers.Transformer{
 		"None":                  passthrough.New(),
@@ -149,57 +913,122 @@ func ErrorModal(message string, window fyne.Window) {
 	})
 }
 
-func (a *appState) InitializeSource() (datasources.DataSourcer, error) {
+func (a *appState) InitializeSource(ctx context.Context) (datasources.DataSourcer, error) {
+	var dataSource datasources.DataSourcer
 	switch a.dataSourceType {
 	case "Dummy":
-		a.dummySource.ResetIndex()
-		return a.dummySource, nil
+		dataSource = a.dummySource
 	case "Serial":
-		err := a.serialSource.OpenPort()
+		dataSource = a.serialSource
+		if a.autoReconnect {
+			supervisor := serial.NewSupervisor(a.serialSource)
+			supervisor.SetOnStatus(func(status string) {
+				fyne.Do(func() {
+					a.serialStatus.SetText(status)
+					a.serialStatus.Show()
+				})
+			})
+			dataSource = supervisor
+		}
+		if a.serialSource.AutoBaud() {
+			fyne.Do(func() {
+				a.serialStatus.SetText("Detecting baud...")
+				a.serialStatus.Show()
+			})
+		}
+	case "TCP":
+		a.tcpSource.SetDecoder(a.serialSource.NewDecoder())
+		dataSource = a.tcpSource
+	case "UDP":
+		a.udpSource.SetDecoder(a.serialSource.NewDecoder())
+		dataSource = a.udpSource
+	case "Pipe":
+		a.pipeSource.SetDecoder(a.serialSource.NewDecoder())
+		dataSource = a.pipeSource
+	case "MQTT":
+		dataSource = a.mqttSource
+	case "Web":
+		dataSource = a.webSource
+	case "Modbus":
+		dataSource = a.modbusSource
+	case "Firmata":
+		dataSource = a.firmataSource
+	case "Replay":
+		a.replaySource.SetDecoder(a.serialSource.NewDecoder())
+		dataSource = a.replaySource
+	default:
+		return nil, fmt.Errorf("unknown data source selected")
+	}
+	if monitored, ok := dataSource.(datasources.Monitored); ok {
+		monitored.SetMonitor(a.monitor.add)
+	}
+	err := dataSource.Open(ctx)
+	if a.dataSourceType == "Serial" && a.serialSource.AutoBaud() {
+		status := fmt.Sprintf("Detected %d baud", a.serialSource.Baud())
 		if err != nil {
-			fmt.Println("error opening port ", err)
-			ErrorModal(fmt.Sprintf("Error opening port %s", err), a.window)
-			return nil, err
+			status = "Baud not detected"
 		}
-		return a.serialSource, nil
+		fyne.Do(func() {
+			a.serialStatus.SetText(status)
+		})
 	}
-	return nil, fmt.Errorf("unknown data source selected")
+	if err != nil {
+		fmt.Println("error opening data source ", err)
+		ErrorModal(fmt.Sprintf("Error opening data source: %s", err), a.window)
+		return nil, err
+	}
+	return dataSource, nil
 }
 
-func (a *appState) CloseDataSource() error {
-	switch a.dataSourceType {
-	case "Serial":
-		err := a.serialSource.Close()
-		if err != nil {
-			ErrorModal(fmt.Sprintf("Error closing port %s", err), a.window)
-			return err
-		}
+func (a *appState) CloseDataSource(dataSource datasources.DataSourcer) error {
+	err := dataSource.Close()
+	if err != nil {
+		ErrorModal(fmt.Sprintf("Error closing data source %s", err), a.window)
+		return err
 	}
 	return nil
 }
 
-func (a *appState) ControlsPanel(dataChannel chan float32, clearChannel chan int, window fyne.Window) *fyne.Container {
-	stop := make(chan int)
+func (a *appState) ControlsPanel(dataChannel chan []datasources.Sample, clearChannel chan int, window fyne.Window) *fyne.Container {
+	var stop context.CancelFunc
 	var startButtonContainer *fyne.Container
 	var stopButtonContainer *fyne.Container
 	stopButton := widget.NewButton("Stop", func() {
-		stop <- 0
+		stop()
+		if recording := a.recording.Load(); recording != nil {
+			err := recording.Flush()
+			if err != nil {
+				fmt.Println("failed to flush recording", err)
+			}
+		}
 	})
 	startButton := widget.NewButton("Start", func() {
 		startButtonContainer.Hide()
 		stopButtonContainer.Show()
+		ctx, cancel := context.WithCancel(context.Background())
+		stop = cancel
 		go func() {
+			defer cancel()
 			defer fyne.Do(func() {
 				startButtonContainer.Show()
 				stopButtonContainer.Hide()
 			})
-			dataSource, err := a.InitializeSource()
+			dataSource, err := a.InitializeSource(ctx)
 			if err != nil {
 				fmt.Println("Failed to initialize data source", err)
 				return
 			}
+			defer func() {
+				err := a.CloseDataSource(dataSource)
+				if err != nil {
+					fmt.Println("failed to close data source", err)
+				}
+			}()
 			for {
-				datum, err := dataSource.ReadSource()
+				data, err := dataSource.Read(ctx)
+				if ctx.Err() != nil || errors.Is(err, io.EOF) {
+					return
+				}
 				if err != nil {
 					if perr, ok := err.(*serial.ParseError); ok {
 						fmt.Println("failed to parse source, skipping", perr)
@@ -209,18 +1038,20 @@ func (a *appState) ControlsPanel(dataChannel chan float32, clearChannel chan int
 					return
 				}
 				select {
-				case <-stop:
-					err := a.CloseDataSource()
-					if err != nil {
-						fmt.Println("failed to close data source", err)
-					}
+				case <-ctx.Done():
 					return
-				default:
-					dataChannel <- datum
+				case dataChannel <- data:
 				}
 			}
 		}()
 	})
+	recordCheck := a.RecordToggle()
+	resetButton := widget.NewButton("Reset Board", func() {
+		err := a.serialSource.ResetBoard()
+		if err != nil {
+			ErrorModal(fmt.Sprintf("Error resetting board %s", err), a.window)
+		}
+	})
 	clearButton := widget.NewButton("Clear", func() {
 		clearChannel <- 0
 	})
@@ -230,11 +1061,201 @@ func (a *appState) ControlsPanel(dataChannel chan float32, clearChannel chan int
 	stopButtonContainer = container.NewStack(canvas.NewRectangle(color.RGBA{255, 0, 0, 127}), stopButton)
 	stopButtonContainer.Hide()
 
-	return container.NewVBox(startButtonContainer, stopButtonContainer, clearButton)
+	return container.NewVBox(startButtonContainer, stopButtonContainer, recordCheck, resetButton, clearButton)
+}
+
+func (a *appState) RecordToggle() *widget.Check {
+	var recordCheck *widget.Check
+	recordCheck = widget.NewCheck("Record", func(checked bool) {
+		if !checked {
+			a.StopRecording()
+			return
+		}
+		fileSave := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				if err != nil {
+					ErrorModal(fmt.Sprintf("Error choosing recording file %s", err), a.window)
+				}
+				recordCheck.SetChecked(false)
+				return
+			}
+			// The first file is the one chosen, rotated files are created
+			// next to it through storage so non-file URIs work
+			first := writer
+			recording := recorder.NewCSV(writer.URI().String(), recorder.DefaultMaxRows)
+			recording.SetCreate(func(path string) (io.WriteCloser, error) {
+				if first != nil {
+					file := first
+					first = nil
+					return file, nil
+				}
+				uri, err := storage.ParseURI(path)
+				if err != nil {
+					return nil, err
+				}
+				return storage.Writer(uri)
+			})
+			err = recording.Open()
+			if err != nil {
+				writer.Close()
+				ErrorModal(fmt.Sprintf("Error opening recording %s", err), a.window)
+				recordCheck.SetChecked(false)
+				return
+			}
+			a.recording.Store(recording)
+		}, a.window)
+		fileSave.SetFileName("recording.csv")
+		fileSave.Show()
+	})
+	return recordCheck
+}
+
+// CaptureToggle tees the raw bytes read from the serial port to a capture
+// file that can be replayed or re-parsed with cmd/reparse.
+func (a *appState) CaptureToggle() *widget.Check {
+	var captureCheck *widget.Check
+	captureCheck = widget.NewCheck("Capture Raw", func(checked bool) {
+		if !checked {
+			a.StopCapture()
+			return
+		}
+		fileSave := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				if err != nil {
+					ErrorModal(fmt.Sprintf("Error choosing capture file %s", err), a.window)
+				}
+				captureCheck.SetChecked(false)
+				return
+			}
+			captureWriter, err := capture.NewWriter(writer)
+			if err != nil {
+				ErrorModal(fmt.Sprintf("Error opening capture %s", err), a.window)
+				captureCheck.SetChecked(false)
+				return
+			}
+			a.capture = captureWriter
+			a.serialSource.SetCapture(captureWriter)
+		}, a.window)
+		fileSave.SetFileName("capture.bin")
+		fileSave.Show()
+	})
+	return captureCheck
+}
+
+func (a *appState) StopCapture() {
+	if a.capture == nil {
+		return
+	}
+	a.serialSource.SetCapture(nil)
+	err := a.capture.Close()
+	a.capture = nil
+	if err != nil {
+		ErrorModal(fmt.Sprintf("Error closing capture %s", err), a.window)
+	}
+}
+
+func (a *appState) StopRecording() {
+	recording := a.recording.Swap(nil)
+	if recording == nil {
+		return
+	}
+	err := recording.Close()
+	if err != nil {
+		ErrorModal(fmt.Sprintf("Error closing recording %s", err), a.window)
+	}
+}
+
+func (a *appState) record(kind string, sample datasources.Sample) {
+	recording := a.recording.Load()
+	if recording == nil {
+		return
+	}
+	err := recording.Write(kind, sample)
+	if err != nil {
+		fmt.Println("failed to record sample", err)
+	}
+}
+
+func (a *appState) clearData() {
+	a.data = map[string][]datasources.Sample{}
+	a.channels = []string{}
+	a.start = nil
+	a.clock = datasources.NewDeviceClock()
+}
+
+func (a *appState) appendData(values []datasources.Sample) {
+	for _, value := range values {
+		if a.start == nil {
+			start := value
+			a.start = &start
+		}
+		data, ok := a.data[value.Name]
+		if !ok {
+			a.channels = append(a.channels, value.Name)
+		}
+		a.record(recorder.Raw, value)
+		plotted, restarted := a.clock.Monotonic(value)
+		if restarted && len(data) > 0 && !math.IsNaN(float64(data[len(data)-1].Value)) {
+			// The device clock went back, start a new segment after the last
+			// point rather than drawing back over it
+			gap := data[len(data)-1]
+			gap.Value = float32(math.NaN())
+			gap.Received = value.Received
+			data = append(data, gap)
+		}
+		transformed := plotted
+		if !math.IsNaN(float64(plotted.Value)) {
+			// Restart the transform after a gap so it never sees NaN
+			segment := data[gapIndex(data):]
+			transformed = a.transform.Compute(segment, plotted)
+		} else if !plotted.HasDeviceTime && len(data) > 0 && data[len(data)-1].HasDeviceTime {
+			// A gap marked by the source rather than the device has no device
+			// time, place it with the point it follows so it splits the series
+			// there rather than at its received time
+			transformed.DeviceTime = data[len(data)-1].DeviceTime
+			transformed.HasDeviceTime = true
+		}
+		// Record the times the device sent rather than those used to plot
+		recorded := transformed
+		recorded.DeviceTime = value.DeviceTime
+		recorded.HasDeviceTime = value.HasDeviceTime
+		a.record(recorder.Transformed, recorded)
+		a.data[value.Name] = append(data, transformed)
+	}
+}
+
+func gapIndex(data []datasources.Sample) int {
+	for index := len(data) - 1; index >= 0; index-- {
+		if math.IsNaN(float64(data[index].Value)) {
+			return index + 1
+		}
+	}
+	return 0
+}
+
+// sampleTime is the time in seconds since the first sample after a clear,
+// preferring the device timestamp when the device supplies one.
+func (a *appState) sampleTime(sample datasources.Sample) float32 {
+	if sample.HasDeviceTime && a.start.HasDeviceTime {
+		return float32((sample.DeviceTime - a.start.DeviceTime).Seconds())
+	}
+	return float32(sample.Received.Sub(a.start.Received).Seconds())
+}
+
+func (a *appState) series() []graph.Series {
+	series := []graph.Series{}
+	for _, name := range a.channels {
+		points := make([]graph.Point, len(a.data[name]))
+		for index, sample := range a.data[name] {
+			points[index] = graph.Point{X: a.sampleTime(sample), Y: sample.Value}
+		}
+		series = append(series, graph.Series{Name: name, Points: points})
+	}
+	return series
 }
 
 func Main() {
-	dataChannel := make(chan float32)
+	dataChannel := make(chan []datasources.Sample)
 	clearChannel := make(chan int)
 
 	app := app.New()
@@ -243,32 +1264,58 @@ func Main() {
 	appState.window = window
 
 	window.Resize(fyne.NewSize(800, 800))
+	app.Lifecycle().SetOnStopped(func() {
+		appState.StopRecording()
+		appState.StopCapture()
+	})
 
 	serialOptions, err := appState.SerialSourceOptions()
 	if err != nil {
 		fmt.Println("failed to create serial source options")
 	}
+	tcpOptions := appState.TCPSourceOptions()
+	udpOptions := appState.UDPSourceOptions()
+	pipeOptions := appState.PipeSourceOptions()
+	mqttOptions := appState.MQTTSourceOptions()
+	webOptions := appState.WebSourceOptions()
+	modbusOptions := appState.ModbusSourceOptions()
+	firmataOptions := appState.FirmataSourceOptions()
 	dummyOptions := appState.DummySourceOptions()
+	replayOptions := appState.ReplaySourceOptions(clearChannel)
 	controlsPanel := appState.ControlsPanel(dataChannel, clearChannel, window)
-	dataSourcesPanel := appState.DataSourcesPanel(serialOptions, dummyOptions)
+	dataSourcesPanel := appState.DataSourcesPanel(map[string]*fyne.Container{
+		"Serial":  serialOptions,
+		"TCP":     tcpOptions,
+		"UDP":     udpOptions,
+		"Pipe":    pipeOptions,
+		"MQTT":    mqttOptions,
+		"Web":     webOptions,
+		"Modbus":  modbusOptions,
+		"Firmata": firmataOptions,
+		"Dummy":   dummyOptions,
+		"Replay":  replayOptions,
+	})
 	transformOptions := appState.TransformOptions()
-	options := container.NewGridWithColumns(4, dataSourcesPanel, serialOptions, dummyOptions, transformOptions, controlsPanel)
+	options := container.NewGridWithColumns(4, dataSourcesPanel, serialOptions, tcpOptions, udpOptions, pipeOptions, mqttOptions, webOptions, modbusOptions, firmataOptions, dummyOptions, replayOptions, transformOptions, controlsPanel)
 	graphContainer := container.NewWithoutLayout()
-	content := container.NewBorder(options, nil, nil, nil, graphContainer)
+	consolePanel := appState.ConsolePanel()
+	plotSplit := container.NewHSplit(graphContainer, appState.MonitorPanel())
+	plotSplit.Offset = 0.75
+	content := container.NewBorder(options, consolePanel, nil, nil, plotSplit)
 
 	window.SetContent(content)
-	appState.data = []float32{}
+	appState.clearData()
 	graphStruct := graph.GraphStruct{}
 	graphStruct.Show(graphContainer)
 	go func() {
 		for {
 			select {
-			case value := <-dataChannel:
-				appState.data = append(appState.data, appState.transform.Compute(appState.data, value))
+			case values := <-dataChannel:
+				appState.appendData(values)
 			case <-clearChannel:
-				appState.data = []float32{}
+				appState.clearData()
 			}
-			graphStruct.Update(graphContainer, appState.data)
+			graphStruct.Update(graphContainer, appState.series())
 			fyne.Do(func() {
 				graphContainer.Refresh()
 			})
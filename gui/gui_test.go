@@ -0,0 +1,94 @@
+package gui
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+	"github.com/taylorcoons/serial-plotter/transformers/passthrough"
+)
+
+func newTestState() *appState {
+	a := &appState{transform: passthrough.New()}
+	a.clearData()
+	return a
+}
+
+func TestAppendDataDeviceClockRestart(t *testing.T) {
+	start := time.Now()
+	sample := func(value float32, received, device time.Duration) datasources.Sample {
+		return datasources.Sample{Name: "a", Value: value, Received: start.Add(received), DeviceTime: device, HasDeviceTime: true}
+	}
+	a := newTestState()
+	a.appendData([]datasources.Sample{
+		sample(1, 0, 10*time.Second),
+		sample(2, time.Second, 11*time.Second),
+		// The board resets and its clock starts again
+		sample(3, 3*time.Second, 0),
+		sample(4, 4*time.Second, time.Second),
+	})
+	points := a.series()[0].Points
+	wantX := []float32{0, 1, 1, 3, 4}
+	if len(points) != len(wantX) {
+		t.Fatalf("points = %v, want %d", points, len(wantX))
+	}
+	for i, x := range wantX {
+		if points[i].X != x {
+			t.Errorf("point %d X = %v, want %v", i, points[i].X, x)
+		}
+	}
+	if !math.IsNaN(float64(points[2].Y)) {
+		t.Errorf("point 2 Y = %v, want a gap before the restart", points[2].Y)
+	}
+}
+
+func TestAppendDataGapMarker(t *testing.T) {
+	start := time.Now()
+	a := newTestState()
+	a.appendData([]datasources.Sample{
+		{Name: "a", Value: 1, Received: start, DeviceTime: 100 * time.Second, HasDeviceTime: true},
+		{Name: "a", Value: 2, Received: start.Add(time.Second), DeviceTime: 105 * time.Second, HasDeviceTime: true},
+		// A reconnect gap from the supervisor only has a received time
+		{Name: "a", Value: float32(math.NaN()), Received: start.Add(30 * time.Second)},
+	})
+	points := a.series()[0].Points
+	if len(points) != 3 || points[2].X != 5 || !math.IsNaN(float64(points[2].Y)) {
+		t.Errorf("points = %v, want the gap at 5s after the last point", points)
+	}
+}
+
+func TestAppendDataChannels(t *testing.T) {
+	start := time.Now()
+	a := newTestState()
+	a.appendData([]datasources.Sample{
+		{Name: "RandomWalk", Value: 1, Received: start},
+		{Name: "Ramp", Value: 10, Received: start},
+	})
+	a.appendData([]datasources.Sample{
+		{Name: "Ramp", Value: 11, Received: start.Add(time.Second)},
+		{Name: "RandomWalk", Value: 2, Received: start.Add(time.Second)},
+	})
+	series := a.series()
+	want := []struct {
+		name   string
+		values []float32
+	}{
+		{"RandomWalk", []float32{1, 2}},
+		{"Ramp", []float32{10, 11}},
+	}
+	if len(series) != len(want) {
+		t.Fatalf("series = %v, want %d", series, len(want))
+	}
+	for i, w := range want {
+		if series[i].Name != w.name || len(series[i].Points) != len(w.values) {
+			t.Errorf("series %d = %v, want %s with %v", i, series[i], w.name, w.values)
+			continue
+		}
+		for j, value := range w.values {
+			if series[i].Points[j].Y != value || series[i].Points[j].X != float32(j) {
+				t.Errorf("series %s point %d = %v, want %v at %ds", w.name, j, series[i].Points[j], value, j)
+			}
+		}
+	}
+}
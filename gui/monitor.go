@@ -0,0 +1,128 @@
+package gui
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+const (
+	maxMonitorLines        = 1000
+	monitorRefreshInterval = 200 * time.Millisecond
+)
+
+// monitor keeps the most recent raw lines received from the data source.
+type monitor struct {
+	mutex   sync.Mutex
+	lines   []datasources.RawLine
+	changed bool
+}
+
+func (m *monitor) add(line datasources.RawLine) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lines = append(m.lines, line)
+	if len(m.lines) > maxMonitorLines {
+		m.lines = m.lines[len(m.lines)-maxMonitorLines:]
+	}
+	m.changed = true
+}
+
+func (m *monitor) clear() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lines = nil
+	m.changed = true
+}
+
+// snapshot returns a copy of the lines if they changed since the last call.
+func (m *monitor) snapshot() ([]datasources.RawLine, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.changed {
+		return nil, false
+	}
+	m.changed = false
+	return append([]datasources.RawLine{}, m.lines...), true
+}
+
+func formatRawLine(line datasources.RawLine, showHex bool) string {
+	text := line.Text
+	if showHex {
+		text = strings.ToUpper(hex.EncodeToString([]byte(line.Text)))
+		pairs := []string{}
+		for i := 0; i < len(text); i += 2 {
+			pairs = append(pairs, text[i:i+2])
+		}
+		text = strings.Join(pairs, " ")
+	}
+	return line.Received.Format("15:04:05.000") + "  " + text
+}
+
+// MonitorPanel shows the raw lines received by the data source with lines
+// that failed to parse highlighted. Pausing freezes the view while lines
+// keep being collected.
+func (a *appState) MonitorPanel() fyne.CanvasObject {
+	a.monitor = &monitor{}
+	view := []datasources.RawLine{}
+	hexCheck := widget.NewCheck("Hex", nil)
+	pauseCheck := widget.NewCheck("Pause", nil)
+	autoscrollCheck := widget.NewCheck("Autoscroll", nil)
+	autoscrollCheck.SetChecked(true)
+	list := widget.NewList(
+		func() int {
+			return len(view)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.TextStyle.Monospace = true
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			label := item.(*widget.Label)
+			line := view[id]
+			if line.Err != nil {
+				label.Importance = widget.DangerImportance
+			} else {
+				label.Importance = widget.MediumImportance
+			}
+			label.SetText(formatRawLine(line, hexCheck.Checked))
+		},
+	)
+	hexCheck.OnChanged = func(bool) { list.Refresh() }
+	clearButton := widget.NewButton("Clear", func() {
+		a.monitor.clear()
+	})
+	paused := atomic.Bool{}
+	pauseCheck.OnChanged = func(checked bool) { paused.Store(checked) }
+	go func() {
+		ticker := time.NewTicker(monitorRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if paused.Load() {
+				continue
+			}
+			lines, changed := a.monitor.snapshot()
+			if !changed {
+				continue
+			}
+			fyne.Do(func() {
+				view = lines
+				list.Refresh()
+				if autoscrollCheck.Checked {
+					list.ScrollToBottom()
+				}
+			})
+		}
+	}()
+	toolbar := container.NewHBox(hexCheck, pauseCheck, autoscrollCheck, clearButton)
+	return container.NewBorder(toolbar, nil, nil, nil, list)
+}
@@ -0,0 +1,56 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func TestFormatRawLine(t *testing.T) {
+	received := time.Date(2024, 1, 2, 13, 4, 5, 678000000, time.Local)
+	tests := []struct {
+		text    string
+		showHex bool
+		want    string
+	}{
+		{text: "a:1,b:2", want: "13:04:05.678  a:1,b:2"},
+		{text: "a:1", showHex: true, want: "13:04:05.678  61 3A 31"},
+		{text: "\x00\xff", showHex: true, want: "13:04:05.678  00 FF"},
+		{text: "", showHex: true, want: "13:04:05.678  "},
+	}
+	for _, test := range tests {
+		got := formatRawLine(datasources.RawLine{Received: received, Text: test.text}, test.showHex)
+		if got != test.want {
+			t.Errorf("formatRawLine(%q, %t) = %q, want %q", test.text, test.showHex, got, test.want)
+		}
+	}
+}
+
+func TestMonitorBounded(t *testing.T) {
+	m := &monitor{}
+	if _, changed := m.snapshot(); changed {
+		t.Error("snapshot of a new monitor reported a change")
+	}
+	for i := 0; i < maxMonitorLines+5; i++ {
+		m.add(datasources.RawLine{Text: fmt.Sprint(i)})
+	}
+	m.add(datasources.RawLine{Text: "bad", Err: errors.New("failed to parse")})
+	lines, changed := m.snapshot()
+	if !changed || len(lines) != maxMonitorLines {
+		t.Fatalf("snapshot = %d lines, %t, want %d, true", len(lines), changed, maxMonitorLines)
+	}
+	if lines[0].Text != "6" || lines[len(lines)-1].Err == nil {
+		t.Errorf("snapshot kept %q to %q, want the newest lines", lines[0].Text, lines[len(lines)-1].Text)
+	}
+	if _, changed := m.snapshot(); changed {
+		t.Error("second snapshot reported a change")
+	}
+	m.clear()
+	lines, changed = m.snapshot()
+	if !changed || len(lines) != 0 {
+		t.Errorf("snapshot after clear = %v, %t, want empty and changed", lines, changed)
+	}
+}
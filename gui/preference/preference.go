@@ -8,16 +8,108 @@ const (
 	Transform
 	PortName
 	Baud
+	Terminator
+	NonFinite
+	TimestampField
+	TimestampUnit
+	ReplayFile
+	ReplaySpeed
+	DataBits
+	Parity
+	StopBits
+	FlowControl
+	DTR
+	RTS
+	DiscardMs
+	DiscardLines
+	AutoReconnect
+	LineEnding
+	HexSend
+	MacroNames
+	MacroCommands
+	TCPMode
+	TCPAddress
+	UDPAddress
+	UDPPrefixSenders
+	PipeKind
+	PipeTarget
+	MQTTBroker
+	MQTTTopics
+	MQTTQoS
+	MQTTUsername
+	WebURL
+	WebMapping
+	WebTimeField
+	WebTimeUnit
+	ModbusTransport
+	ModbusAddress
+	ModbusRegisters
+	ModbusInterval
+	Framing
+	Layout
+	ByteOrder
+	FirmataPins
+	FirmataInterval
+	Grammar
 )
 
 var preferenceKey = map[Preference]string{
-	DataSource: "DataSource",
-	Function:   "Function",
-	Transform:  "Transform",
-	PortName:   "PortName",
-	Baud:       "Baud",
+	DataSource:       "DataSource",
+	Function:         "Function",
+	Transform:        "Transform",
+	PortName:         "PortName",
+	Baud:             "Baud",
+	Terminator:       "Terminator",
+	NonFinite:        "NonFinite",
+	TimestampField:   "TimestampField",
+	TimestampUnit:    "TimestampUnit",
+	ReplayFile:       "ReplayFile",
+	ReplaySpeed:      "ReplaySpeed",
+	DataBits:         "DataBits",
+	Parity:           "Parity",
+	StopBits:         "StopBits",
+	FlowControl:      "FlowControl",
+	DTR:              "DTR",
+	RTS:              "RTS",
+	DiscardMs:        "DiscardMs",
+	DiscardLines:     "DiscardLines",
+	AutoReconnect:    "AutoReconnect",
+	LineEnding:       "LineEnding",
+	HexSend:          "HexSend",
+	MacroNames:       "MacroNames",
+	MacroCommands:    "MacroCommands",
+	TCPMode:          "TCPMode",
+	TCPAddress:       "TCPAddress",
+	UDPAddress:       "UDPAddress",
+	UDPPrefixSenders: "UDPPrefixSenders",
+	PipeKind:         "PipeKind",
+	PipeTarget:       "PipeTarget",
+	MQTTBroker:       "MQTTBroker",
+	MQTTTopics:       "MQTTTopics",
+	MQTTQoS:          "MQTTQoS",
+	MQTTUsername:     "MQTTUsername",
+	WebURL:           "WebURL",
+	WebMapping:       "WebMapping",
+	WebTimeField:     "WebTimeField",
+	WebTimeUnit:      "WebTimeUnit",
+	ModbusTransport:  "ModbusTransport",
+	ModbusAddress:    "ModbusAddress",
+	ModbusRegisters:  "ModbusRegisters",
+	ModbusInterval:   "ModbusInterval",
+	Framing:          "Framing",
+	Layout:           "Layout",
+	ByteOrder:        "ByteOrder",
+	FirmataPins:      "FirmataPins",
+	FirmataInterval:  "FirmataInterval",
+	Grammar:          "Grammar",
 }
 
 func (p Preference) String() string {
 	return preferenceKey[p]
 }
+
+// For returns the key of a preference kept separately for each name, such
+// as a setting remembered for each serial port.
+func (p Preference) For(name string) string {
+	return p.String() + ":" + name
+}
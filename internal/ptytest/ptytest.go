@@ -0,0 +1,36 @@
+//go:build linux
+
+// Package ptytest provides pseudo-terminals standing in for serial ports in
+// tests.
+package ptytest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// Open opens a pseudo-terminal pair, returning the master and the path of
+// the slave which stands in for a serial port. The test is skipped where
+// pseudo-terminals are unavailable.
+func Open(t testing.TB) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals unavailable: %v", err)
+	}
+	fd := int(master.Fd())
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		master.Close()
+		t.Fatalf("failed to unlock pty: %v", err)
+	}
+	number, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		t.Fatalf("failed to get pty number: %v", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", number)
+}
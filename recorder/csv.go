@@ -0,0 +1,208 @@
+package recorder
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+const (
+	Raw         = "raw"
+	Transformed = "transformed"
+
+	DefaultMaxRows       = 1000000
+	DefaultFlushInterval = time.Second
+)
+
+var CSVHeader = []string{"time", "elapsed_s", "device_time_s", "channel", "kind", "value"}
+
+// CSVRecorder streams samples to CSV files. A new file is started every
+// maxRows rows, named after the chosen path with an increasing suffix
+// (run.csv, run-001.csv, run-002.csv, ...). Rows are flushed in the
+// background every flush interval so at most that much data is lost if the
+// application dies.
+type CSVRecorder struct {
+	mutex         sync.Mutex
+	path          string
+	maxRows       int
+	flushInterval time.Duration
+	create        func(path string) (io.WriteCloser, error)
+	file          io.WriteCloser
+	writer        *csv.Writer
+	rows          int
+	index         int
+	start         time.Time
+	done          chan struct{}
+}
+
+func NewCSV(path string, maxRows int) *CSVRecorder {
+	if maxRows <= 0 {
+		maxRows = DefaultMaxRows
+	}
+	return &CSVRecorder{
+		path:          path,
+		maxRows:       maxRows,
+		flushInterval: DefaultFlushInterval,
+		create: func(path string) (io.WriteCloser, error) {
+			return os.Create(path)
+		},
+	}
+}
+
+// SetCreate sets how each file of the recording is created, such as through
+// a storage API rather than the file system. The path is the chosen path
+// with the rotation suffix added.
+func (r *CSVRecorder) SetCreate(create func(path string) (io.WriteCloser, error)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.create = create
+}
+
+func (r *CSVRecorder) Open() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.index = 0
+	r.start = time.Now()
+	err := r.openFile()
+	if err != nil {
+		return err
+	}
+	r.done = make(chan struct{})
+	go r.flushPeriodically(r.done)
+	return nil
+}
+
+func (r *CSVRecorder) flushPeriodically(done chan struct{}) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := r.Flush()
+			if err != nil {
+				fmt.Println("failed to flush recording", err)
+			}
+		}
+	}
+}
+
+func (r *CSVRecorder) filePath() string {
+	return RotatedPath(r.path, r.index)
+}
+
+// RotatedPath returns the path of file index of a recording started at
+// path, index 0 being path itself.
+func RotatedPath(path string, index int) string {
+	if index == 0 {
+		return path
+	}
+	extension := filepath.Ext(path)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(path, extension), index, extension)
+}
+
+func (r *CSVRecorder) openFile() error {
+	file, err := r.create(r.filePath())
+	if err != nil {
+		return fmt.Errorf("failed to create recording %v", err)
+	}
+	r.file = file
+	r.writer = csv.NewWriter(file)
+	r.rows = 0
+	return r.writer.Write(CSVHeader)
+}
+
+func (r *CSVRecorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	r.writer.Flush()
+	err := r.writer.Error()
+	if syncErr := r.sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	r.writer = nil
+	return err
+}
+
+// Write records a sample as either a Raw or Transformed row.
+func (r *CSVRecorder) Write(kind string, sample datasources.Sample) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		return fmt.Errorf("recording is not open")
+	}
+	if r.rows >= r.maxRows {
+		err := r.closeFile()
+		if err != nil {
+			return err
+		}
+		r.index++
+		err = r.openFile()
+		if err != nil {
+			return err
+		}
+	}
+	deviceTime := ""
+	if sample.HasDeviceTime {
+		deviceTime = strconv.FormatFloat(sample.DeviceTime.Seconds(), 'f', -1, 64)
+	}
+	err := r.writer.Write([]string{
+		sample.Received.Format(time.RFC3339Nano),
+		strconv.FormatFloat(sample.Received.Sub(r.start).Seconds(), 'f', -1, 64),
+		deviceTime,
+		sample.Name,
+		kind,
+		strconv.FormatFloat(float64(sample.Value), 'g', -1, 32),
+	})
+	if err != nil {
+		return err
+	}
+	r.rows++
+	return nil
+}
+
+// Flush writes any buffered rows through to the file.
+func (r *CSVRecorder) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	r.writer.Flush()
+	if err := r.writer.Error(); err != nil {
+		return err
+	}
+	return r.sync()
+}
+
+// sync commits the file to disk where the writer supports it.
+func (r *CSVRecorder) sync() error {
+	if syncer, ok := r.file.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+func (r *CSVRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+	return r.closeFile()
+}
@@ -0,0 +1,173 @@
+package recorder
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taylorcoons/serial-plotter/datasources"
+)
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestCSVRotation(t *testing.T) {
+	dir := t.TempDir()
+	recording := NewCSV(filepath.Join(dir, "run.csv"), 2)
+	err := recording.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := time.Now()
+	samples := []datasources.Sample{
+		{Name: "a", Value: 1, Received: received},
+		{Name: "b", Value: 2.5, Received: received},
+		{Name: "a", Value: -3, Received: received, DeviceTime: 1500 * time.Millisecond, HasDeviceTime: true},
+		{Name: "a", Value: 4, Received: received},
+		{Name: "b", Value: 5, Received: received},
+	}
+	for i, sample := range samples {
+		kind := Raw
+		if i == 3 {
+			kind = Transformed
+		}
+		err := recording.Write(kind, sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = recording.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	wantNames := []string{"run-001.csv", "run-002.csv", "run.csv"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("files = %v, want %v", names, wantNames)
+	}
+
+	wantRows := map[string][][]string{
+		"run.csv":     {{"a", Raw, "", "1"}, {"b", Raw, "", "2.5"}},
+		"run-001.csv": {{"a", Raw, "1.5", "-3"}, {"a", Transformed, "", "4"}},
+		"run-002.csv": {{"b", Raw, "", "5"}},
+	}
+	for name, want := range wantRows {
+		rows := readCSV(t, filepath.Join(dir, name))
+		if !reflect.DeepEqual(rows[0], CSVHeader) {
+			t.Errorf("%s header = %v, want %v", name, rows[0], CSVHeader)
+		}
+		got := [][]string{}
+		for _, row := range rows[1:] {
+			if row[0] != received.Format(time.RFC3339Nano) {
+				t.Errorf("%s time = %s, want %s", name, row[0], received.Format(time.RFC3339Nano))
+			}
+			got = append(got, []string{row[3], row[4], row[2], row[5]})
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s rows = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCSVPeriodicFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.csv")
+	recording := NewCSV(path, DefaultMaxRows)
+	recording.flushInterval = 10 * time.Millisecond
+	err := recording.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recording.Close()
+	err = recording.Write(Raw, datasources.Sample{Name: "a", Value: 1, Received: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(string(data), "\n") == 2 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("recording was not flushed, it holds %q", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCSVWriteBeforeOpen(t *testing.T) {
+	recording := NewCSV(filepath.Join(t.TempDir(), "run.csv"), DefaultMaxRows)
+	err := recording.Write(Raw, datasources.Sample{Name: "a"})
+	if err == nil {
+		t.Error("Write before Open succeeded")
+	}
+}
+
+type memoryFile struct {
+	strings.Builder
+	closed bool
+}
+
+func (f *memoryFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestCSVSetCreate(t *testing.T) {
+	files := map[string]*memoryFile{}
+	recording := NewCSV("content://recordings/run.csv", 1)
+	recording.SetCreate(func(path string) (io.WriteCloser, error) {
+		files[path] = &memoryFile{}
+		return files[path], nil
+	})
+	err := recording.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []float32{1, 2} {
+		err := recording.Write(Raw, datasources.Sample{Name: "a", Value: value, Received: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = recording.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"content://recordings/run.csv", "content://recordings/run-001.csv"} {
+		file, ok := files[path]
+		if !ok {
+			t.Fatalf("files = %v, want %s", files, path)
+		}
+		if !file.closed || strings.Count(file.String(), "\n") != 2 {
+			t.Errorf("%s closed = %v with %q, want closed with a header and a row", path, file.closed, file.String())
+		}
+	}
+}
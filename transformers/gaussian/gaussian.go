@@ -4,6 +4,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/taylorcoons/serial-plotter/datasources"
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
@@ -22,6 +23,7 @@ func New(mean float32, standardDeviation float32) *Guassian {
 	}
 }
 
-func (g *Guassian) Compute(data []float32, datum float32) float32 {
-	return datum + float32(g.normalDistribution.Rand())
+func (g *Guassian) Compute(data []datasources.Sample, sample datasources.Sample) datasources.Sample {
+	sample.Value += float32(g.normalDistribution.Rand())
+	return sample
 }
@@ -1,5 +1,10 @@
 package transformers
 
+import "github.com/taylorcoons/serial-plotter/datasources"
+
+// Transformer computes the next plotted sample of a channel from the
+// samples already plotted. The returned sample keeps the timestamps of the
+// input sample.
 type Transformer interface {
-	Compute(data []float32, datum float32) float32
+	Compute(data []datasources.Sample, sample datasources.Sample) datasources.Sample
 }
@@ -1,11 +1,13 @@
 package passthrough
 
+import "github.com/taylorcoons/serial-plotter/datasources"
+
 type Passthrough struct{}
 
 func New() *Passthrough {
 	return &Passthrough{}
 }
 
-func (p *Passthrough) Compute(data []float32, datum float32) float32 {
-	return datum
+func (p *Passthrough) Compute(data []datasources.Sample, sample datasources.Sample) datasources.Sample {
+	return sample
 }
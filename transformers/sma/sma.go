@@ -1,5 +1,7 @@
 package sma
 
+import "github.com/taylorcoons/serial-plotter/datasources"
+
 type Sma struct {
 	k int
 }
@@ -10,14 +12,15 @@ func New(k int) *Sma {
 	}
 }
 
-func (s *Sma) Compute(data []float32, datum float32) float32 {
+func (s *Sma) Compute(data []datasources.Sample, sample datasources.Sample) datasources.Sample {
 	if len(data) < 1 {
-		return datum
+		return sample
 	}
 	k := s.k
 	if len(data) < s.k {
 		k = len(data) + 1
 	}
-	smaPrev := data[len(data)-1]
-	return smaPrev + 1.0/float32(k)*(datum-data[len(data)-k+1])
+	smaPrev := data[len(data)-1].Value
+	sample.Value = smaPrev + 1.0/float32(k)*(sample.Value-data[len(data)-k+1].Value)
+	return sample
 }